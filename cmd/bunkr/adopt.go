@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/hardening"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/reconcile"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var adoptYesFlag bool
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt",
+	Short: "Rebuild state.json from the apps, Caddy, Tailscale and firewall config on the server",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireRemote(); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		exec, err := newExecutor()
+		if err != nil {
+			return err
+		}
+		unlock, err := lockServer(ctx, exec)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		// Lost or corrupt state is what adopt is for
+		current, err := state.Load(ctx, exec)
+		if errors.Is(err, state.ErrCorrupt) {
+			ui.Warn(err.Error())
+			current = state.New()
+		} else if err != nil {
+			return err
+		}
+
+		ui.Header("Scanning server...")
+		res, err := reconcile.Scan(ctx, exec, recipe.FetchVersion)
+		if err != nil {
+			return err
+		}
+		proposed := res.State
+		proposed.Events = current.Events
+
+		// Scanning can't recover when an app was installed or which
+		// settings it was given, so keep what the old state knew
+		for name, app := range proposed.Recipes {
+			if old, ok := current.Recipes[name]; ok {
+				if !old.InstalledAt.IsZero() {
+					app.InstalledAt = old.InstalledAt
+				}
+				app.Settings = old.Settings
+				proposed.Recipes[name] = app
+			}
+		}
+		if proposed.Hardening.Applied && !current.Hardening.AppliedAt.IsZero() {
+			proposed.Hardening.AppliedAt = current.Hardening.AppliedAt
+		}
+
+		printProposedState(current, proposed)
+		for _, note := range res.Notes {
+			ui.Warn(note)
+		}
+
+		if reflect.DeepEqual(current, proposed) {
+			ui.Result("state.json already matches the server")
+			return nil
+		}
+
+		if !adoptYesFlag {
+			answer, err := recipe.PromptUser([]recipe.Prompt{{
+				Key:     "save",
+				Label:   "Save this as the server's state?",
+				Options: []string{"yes", "no"},
+				Default: "no",
+			}})
+			if err != nil {
+				return err
+			}
+			if answer["save"] != "yes" {
+				return fmt.Errorf("adopt aborted, state.json was not changed")
+			}
+		}
+
+		if err := takeSnapshot(ctx, exec); err != nil {
+			return err
+		}
+		proposed.Record(newEvent("", state.EventAdopted, fmt.Sprintf("%d app(s)", len(proposed.Recipes))))
+		if err := state.Save(ctx, exec, proposed); err != nil {
+			return err
+		}
+		rememberApps(serverName, proposed)
+		ui.Result(fmt.Sprintf("State rebuilt with %d app(s)", len(proposed.Recipes)))
+		return nil
+	},
+}
+
+func printProposedState(current, proposed *state.State) {
+	var steps []string
+	for _, step := range hardening.Steps(0) {
+		mark := "✗"
+		if proposed.Hardening.Steps[step.Name] {
+			mark = "✓"
+		}
+		steps = append(steps, mark+" "+step.Name)
+	}
+	fmt.Println()
+	printField("Hardening", strings.Join(steps, "  "))
+	if proposed.Hardening.SSHPort != 0 {
+		printField("SSH port", fmt.Sprint(proposed.Hardening.SSHPort))
+	}
+	tailscale := "not installed"
+	switch {
+	case proposed.Tailscale.Connected:
+		tailscale = "connected as " + proposed.Tailscale.Hostname
+	case proposed.Tailscale.Installed:
+		tailscale = "installed, not connected"
+	}
+	printField("Tailscale", tailscale)
+
+	names := make([]string, 0, len(proposed.Recipes)+len(current.Recipes))
+	for name := range proposed.Recipes {
+		names = append(names, name)
+	}
+	for name := range current.Recipes {
+		if _, ok := proposed.Recipes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Printf("\n  %-20s %-10s %-10s %-10s %s\n", "NAME", "VERSION", "ACCESS", "CHANGE", "ENDPOINTS")
+	for _, name := range names {
+		app, found := proposed.Recipes[name]
+		old, known := current.Recipes[name]
+		change := "same"
+		switch {
+		case !found:
+			fmt.Printf("  %-20s %-10s %-10s %-10s %s\n", name, old.Version, "-", "drop", "not found on the server")
+			continue
+		case !known:
+			change = "new"
+		case !reflect.DeepEqual(old, app):
+			change = "changed"
+		}
+		access := "public"
+		if app.Private {
+			access = "tailscale"
+		}
+		var eps []string
+		for _, ep := range app.Endpoints {
+			route := ep.Domain + ep.Path
+			if route == "" {
+				route = ep.Protocol
+			}
+			eps = append(eps, fmt.Sprintf("%s %s → %d", ep.Name, route, ep.Port))
+		}
+		fmt.Printf("  %-20s %-10s %-10s %-10s %s\n", name, app.Version, access, change, strings.Join(eps, ", "))
+	}
+	fmt.Println()
+}
+
+func init() {
+	adoptCmd.Flags().BoolVarP(&adoptYesFlag, "yes", "y", false, "save the rebuilt state without asking")
+	rootCmd.AddCommand(adoptCmd)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pankajbeniwal/bunkr/internal/doctor"
+	"github.com/pankajbeniwal/bunkr/internal/preflight"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var doctorJSONFlag bool
+
+var doctorCmd = &cobra.Command{
+	Use:          "doctor",
+	Short:        "Check that the server still matches state.json and suggest fixes",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireRemote(); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		exec, err := newExecutor()
+		if err != nil {
+			return err
+		}
+
+		// Read-only, so no lock
+		s, err := loadState(ctx, exec)
+		if err != nil {
+			return err
+		}
+
+		if !doctorJSONFlag {
+			ui.Header("Checking server...")
+		}
+		results, err := doctor.Run(ctx, exec, s)
+		if err != nil {
+			return err
+		}
+		worst := doctor.Worst(results)
+
+		if doctorJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(struct {
+				Status  preflight.Status `json:"status"`
+				Results []doctor.Result  `json:"results"`
+			}{worst, results}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Println()
+			for _, r := range results {
+				ui.CheckRow(r.Status.String(), r.Subject, r.Check, r.Detail)
+				if r.Status != preflight.Pass && r.Fix != "" {
+					fmt.Printf("  %-8s %-16s fix: %s\n", "", "", r.Fix)
+				}
+			}
+			fmt.Println()
+		}
+
+		switch worst {
+		case preflight.Fail:
+			return fmt.Errorf("the server has drifted from state.json, see the fixes above")
+		case preflight.Warn:
+			if !doctorJSONFlag {
+				ui.Warn("No failures, but some checks need attention")
+			}
+		default:
+			if !doctorJSONFlag {
+				ui.Result("Server matches state.json")
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSONFlag, "json", false, "print the report as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
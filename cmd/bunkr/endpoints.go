@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/hardening"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/tailscale"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+)
+
+// scanPorts marks the host ports in use before endpoints are allocated. A
+// host without ss only has bunkr's compose files scanned, with a warning.
+func scanPorts(ctx context.Context, exec executor.Executor, s *state.State) error {
+	err := s.ScanPorts(ctx, exec)
+	if errors.Is(err, state.ErrNoSocketList) {
+		ui.Warn(fmt.Sprintf("%s; install iproute2 so bunkr can avoid them", err))
+		return nil
+	}
+	return err
+}
+
+// allocateEndpoints assigns a host port and domain to each of the recipe's
+// endpoints, in EndpointList order. Endpoints already in previous keep their
+// host port, and their domain when values don't provide one.
+func allocateEndpoints(s *state.State, r *recipe.Recipe, values map[string]string, previous []state.EndpointState) []state.EndpointState {
+	prev := make(map[string]state.EndpointState, len(previous))
+	for _, ep := range previous {
+		prev[ep.Name] = ep
+	}
+
+	var endpoints []state.EndpointState
+	for _, ep := range r.EndpointList() {
+		es := state.EndpointState{
+			Name:          ep.Name,
+			Protocol:      ep.Protocol,
+			Path:          ep.Path,
+			StripPath:     ep.StripPath,
+			ContainerPort: ep.Port,
+		}
+		if !ep.IsTCP() {
+			if r.Private {
+				es.Domain = s.Tailscale.Hostname
+			} else {
+				es.Domain = r.EndpointDomain(ep, values)
+			}
+		}
+
+		if old, ok := prev[ep.Name]; ok {
+			es.Port = old.Port
+			if es.Domain == "" {
+				es.Domain = old.Domain
+			}
+		} else {
+			es.Port = s.AllocatePort(ep.DesiredHostPort())
+		}
+		endpoints = append(endpoints, es)
+	}
+	return endpoints
+}
+
+func hostPorts(endpoints []state.EndpointState) []int {
+	ports := make([]int, len(endpoints))
+	for i, ep := range endpoints {
+		ports[i] = ep.Port
+	}
+	return ports
+}
+
+// exposeEndpoints routes traffic to an app: Tailscale Serve for private apps,
+// Caddy for public http endpoints and a firewall rule for public tcp ones.
+// Caddy is not reloaded here so callers can batch reloads.
+func exposeEndpoints(ctx context.Context, exec executor.Executor, name string, private bool, endpoints []state.EndpointState) error {
+	if private {
+		for _, ep := range endpoints {
+			var err error
+			switch {
+			case ep.Protocol == recipe.ProtocolTCP:
+				err = tailscale.ServeTCP(ctx, exec, ep.Port)
+			case ep.Path != "":
+				err = tailscale.ServePath(ctx, exec, ep.Path, ep.Port)
+			default:
+				err = tailscale.Serve(ctx, exec, ep.Port)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		ui.Success("Tailscale serve configured")
+		return nil
+	}
+
+	var sites []caddy.Site
+	siteIndex := make(map[string]int)
+	for _, ep := range endpoints {
+		if ep.Protocol == recipe.ProtocolTCP {
+			if err := hardening.OpenPort(ctx, exec, ep.Port, "tcp"); err != nil {
+				return err
+			}
+			ui.Success(fmt.Sprintf("Port %d/tcp opened for %s", ep.Port, ep.Name))
+			continue
+		}
+		if ep.Domain == "" {
+			ui.Warn(fmt.Sprintf("No domain for endpoint %s — skipping proxy config", ep.Name))
+			continue
+		}
+		i, ok := siteIndex[ep.Domain]
+		if !ok {
+			i = len(sites)
+			siteIndex[ep.Domain] = i
+			sites = append(sites, caddy.Site{Domain: ep.Domain})
+		}
+		sites[i].Routes = append(sites[i].Routes, caddy.Route{Path: ep.Path, StripPath: ep.StripPath, Port: ep.Port})
+	}
+
+	if len(sites) > 0 {
+		if err := caddy.AddSites(ctx, exec, name, sites); err != nil {
+			return err
+		}
+		ui.Success("Caddy configured")
+	}
+	return nil
+}
+
+// unexposeEndpoints undoes exposeEndpoints, warning rather than failing so
+// uninstall can always finish.
+func unexposeEndpoints(ctx context.Context, exec executor.Executor, name string, private bool, endpoints []state.EndpointState) {
+	if private {
+		for _, ep := range endpoints {
+			var err error
+			switch {
+			case ep.Protocol == recipe.ProtocolTCP:
+				err = tailscale.RemoveServeTCP(ctx, exec, ep.Port)
+			case ep.Path != "":
+				err = tailscale.RemoveServePath(ctx, exec, ep.Path)
+			default:
+				err = tailscale.RemoveServe(ctx, exec, ep.Port)
+			}
+			if err != nil {
+				ui.Warn("Failed to remove Tailscale serve: " + err.Error())
+				return
+			}
+		}
+		ui.Success("Tailscale serve removed")
+		return
+	}
+
+	for _, ep := range endpoints {
+		if ep.Protocol == recipe.ProtocolTCP {
+			if err := hardening.ClosePort(ctx, exec, ep.Port, "tcp"); err != nil {
+				ui.Warn(err.Error())
+			}
+		}
+	}
+	if err := caddy.RemoveBlock(ctx, exec, name); err != nil {
+		ui.Warn("Failed to remove Caddy config: " + err.Error())
+	} else {
+		ui.Success("Caddy config removed")
+	}
+}
+
+func endpointsChanged(a, b []state.EndpointState) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointAddress is how a user reaches an endpoint.
+func endpointAddress(ep state.EndpointState, host string) string {
+	if ep.Protocol == recipe.ProtocolTCP {
+		if host == "" {
+			host = "<server>"
+		}
+		return fmt.Sprintf("%s:%d", host, ep.Port)
+	}
+	return "https://" + ep.Domain + ep.Path
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [app]",
+	Short: "Show what bunkr changed on the server, and who ran it",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireRemote(); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		exec, err := newExecutor()
+		if err != nil {
+			return err
+		}
+		s, err := loadState(ctx, exec)
+		if err != nil {
+			return err
+		}
+
+		app := ""
+		if len(args) > 0 {
+			app = args[0]
+		}
+		events := s.History(app)
+		if len(events) == 0 {
+			if app != "" {
+				ui.Info(fmt.Sprintf("No history for %s", app))
+			} else {
+				ui.Info("No history yet")
+			}
+			return nil
+		}
+
+		fmt.Printf("\n  %-20s %-16s %-13s %-24s %s\n", "TIME", "APP", "EVENT", "BY", "DETAIL")
+		for _, e := range events {
+			subject := e.App
+			if subject == "" {
+				subject = "(server)"
+			}
+			detail := e.Detail
+			if e.Error != "" {
+				detail = e.Error
+			}
+			fmt.Printf("  %-20s %-16s %-13s %-24s %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), subject, e.Kind, e.By, detail)
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+// newEvent returns an event for app attributed to the running command.
+func newEvent(app, kind, detail string) state.Event {
+	user, host := operator()
+	return state.Event{App: app, Kind: kind, Detail: detail, By: user + "@" + host, Command: commandLine()}
+}
+
+// recordFailure adds a failed event to the saved state. The state is
+// reloaded first so half-applied changes held in memory aren't saved with it.
+func recordFailure(ctx context.Context, exec executor.Executor, app string, cause error) {
+	s, err := state.Load(ctx, exec)
+	if err != nil {
+		return
+	}
+	e := newEvent(app, state.EventFailed, "")
+	e.Error = cause.Error()
+	s.Record(e)
+	if err := state.Save(ctx, exec, s); err != nil {
+		ui.Warn("Failed to record the failure in state: " + err.Error())
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
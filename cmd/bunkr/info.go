@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info <recipe>[@version]",
+	Short: "Show what installing a recipe would create",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, version := recipe.ParseRef(args[0])
+		r, err := recipe.FetchVersion(name, version)
+		if err != nil {
+			return err
+		}
+
+		ui.Header(fmt.Sprintf("%s %s", r.Name, r.Version))
+		fmt.Printf("\n  %s\n\n", r.Description)
+		printField("Homepage", r.Homepage)
+		printField("Category", r.Category)
+		printField("Tags", strings.Join(r.Tags, ", "))
+		if r.Private {
+			printField("Access", "private (Tailscale)")
+		} else {
+			printField("Access", "public (Caddy with HTTPS)")
+		}
+		printField("Image", r.Image)
+		printField("Requirements", formatRequirements(r.Requirements))
+
+		if len(r.Prompts) > 0 {
+			ui.Header("Prompts")
+			fmt.Printf("\n  %-24s %-40s %s\n", "KEY", "LABEL", "NOTES")
+			fmt.Printf("  %-24s %-40s %s\n", "---", "-----", "-----")
+			for _, p := range r.Prompts {
+				var notes []string
+				if p.Required {
+					notes = append(notes, "required")
+				}
+				if p.Secret {
+					notes = append(notes, "secret")
+				}
+				if p.Default != "" {
+					notes = append(notes, "default "+p.Default)
+				}
+				if len(p.Options) > 0 {
+					notes = append(notes, "one of "+strings.Join(p.Options, "/"))
+				}
+				fmt.Printf("  %-24s %-40s %s\n", p.Key, p.Label, strings.Join(notes, ", "))
+			}
+		}
+
+		ui.Header("Services")
+		fmt.Printf("\n  %-24s %s\n", "NAME", "IMAGE")
+		fmt.Printf("  %-24s %s\n", "----", "-----")
+		fmt.Printf("  %-24s %s\n", r.Name, r.Image)
+		for _, svc := range r.Services {
+			fmt.Printf("  %-24s %s\n", svc.Name, svc.Image)
+		}
+
+		ui.Header("Endpoints")
+		fmt.Printf("\n  %-16s %-10s %-10s %s\n", "NAME", "PROTOCOL", "PORT", "ROUTE")
+		fmt.Printf("  %-16s %-10s %-10s %s\n", "----", "--------", "----", "-----")
+		for _, ep := range r.EndpointList() {
+			fmt.Printf("  %-16s %-10s %-10d %s\n", ep.Name, ep.Protocol, ep.Port, endpointRoute(r, ep))
+		}
+
+		volumes := recipeVolumes(r)
+		if len(volumes) > 0 {
+			ui.Header("Volumes")
+			fmt.Printf("\n  %-24s %-32s %s\n", "SERVICE", "SOURCE", "MOUNT")
+			fmt.Printf("  %-24s %-32s %s\n", "-------", "------", "-----")
+			for _, v := range volumes {
+				fmt.Printf("  %-24s %-32s %s\n", v[0], v[1], v[2])
+			}
+		}
+
+		if len(r.Files) > 0 {
+			ui.Header("Config files")
+			fmt.Println()
+			for _, f := range r.Files {
+				fmt.Printf("  /opt/bunkr/%s/%s\n", r.Name, f.Path)
+			}
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+func printField(label, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Printf("  %-14s %s\n", label+":", value)
+}
+
+func formatRequirements(req recipe.Requirements) string {
+	var parts []string
+	if req.Memory != "" {
+		parts = append(parts, req.Memory+" RAM")
+	}
+	if req.Disk != "" {
+		parts = append(parts, req.Disk+" free disk")
+	}
+	if len(req.Architectures) > 0 {
+		parts = append(parts, strings.Join(req.Architectures, "/"))
+	}
+	if len(req.Kernel) > 0 {
+		parts = append(parts, "kernel "+strings.Join(req.Kernel, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// endpointRoute describes where an endpoint will be reachable before any
+// values are known.
+func endpointRoute(r *recipe.Recipe, ep recipe.Endpoint) string {
+	switch {
+	case ep.IsTCP():
+		return fmt.Sprintf("host port %d", ep.DesiredHostPort())
+	case r.Private:
+		return "tailscale" + ep.Path
+	case ep.DomainPrompt != "":
+		return "${" + ep.DomainPrompt + "}" + ep.Path
+	}
+	return ep.Path
+}
+
+// recipeVolumes lists each volume as service, source and mount path.
+func recipeVolumes(r *recipe.Recipe) [][3]string {
+	var out [][3]string
+	add := func(service string, volumes []string) {
+		for _, v := range volumes {
+			source, mount, _ := strings.Cut(v, ":")
+			out = append(out, [3]string{service, source, mount})
+		}
+	}
+	add(r.Name, r.Volumes)
+	for _, svc := range r.Services {
+		add(svc.Name, svc.Volumes)
+	}
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
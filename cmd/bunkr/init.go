@@ -20,7 +20,7 @@ var sshPortFlag int
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Harden the server (no app install)",
-	RunE: func(cmd *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		if err := requireRemote(); err != nil {
 			return err
 		}
@@ -30,24 +30,39 @@ var initCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		unlock, err := lockServer(ctx, exec)
+		if err != nil {
+			return err
+		}
+		defer unlock()
 
 		// Set system-wide apt lock timeout (fresh VPS often has apt running)
 		exec.Run(ctx, `echo 'DPkg::Lock::Timeout "120";' > /etc/apt/apt.conf.d/99-bunkr-lock-wait`)
 
 		ui.Header("Hardening VPS...")
 
-		s, err := state.Load(ctx, exec)
+		s, err := loadState(ctx, exec)
 		if err != nil {
 			return err
 		}
 
-		_, err = hardening.Run(ctx, exec, s, sshPortFlag)
+		if err := takeSnapshot(ctx, exec); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				recordFailure(ctx, exec, "", err)
+			}
+		}()
+
+		results, err := hardening.Run(ctx, exec, s, sshPortFlag)
 		if err != nil {
 			return err
 		}
 
 		s.Hardening.AppliedAt = time.Now()
 		s.Hardening.SSHPort = sshPortFlag
+		recordHardening(s, results)
 
 		if err := state.Save(ctx, exec, s); err != nil {
 			return err
@@ -55,6 +70,7 @@ var initCmd = &cobra.Command{
 
 		ui.Result("Server hardened successfully!")
 		ui.HardeningSummary(extractHost(onFlag), sshPortFlag)
+		rememberHardened(sshPortFlag)
 		return nil
 	},
 }
@@ -64,6 +80,16 @@ func init() {
 	rootCmd.AddCommand(initCmd)
 }
 
+// recordHardening adds an event for each hardening step that was applied
+// rather than found already in place.
+func recordHardening(s *state.State, results []hardening.StepResult) {
+	for _, r := range results {
+		if !r.Skipped && r.Error == nil {
+			s.Record(newEvent("", state.EventHardened, r.Name))
+		}
+	}
+}
+
 // extractHost returns just the hostname/IP from a target string like "root@167.71.50.23:22".
 func extractHost(target string) string {
 	if idx := strings.Index(target, "@"); idx != -1 {
@@ -82,9 +108,15 @@ func newExecutor() (executor.Executor, error) {
 	return executor.NewLocalExecutor(), nil
 }
 
+// requireRemote resolves the server a command runs on and checks one can be
+// reached. Only commands that run on a server call it, so local commands
+// never read the inventory.
 func requireRemote() error {
+	if err := resolveServer(); err != nil {
+		return err
+	}
 	if onFlag == "" && runtime.GOOS != "linux" {
-		return fmt.Errorf("--on flag is required on %s (e.g., --on root@167.71.50.23)\n\nbunkr server commands run on Linux. Use --on to target a remote server,\nor run bunkr directly on a Linux machine.", runtime.GOOS)
+		return fmt.Errorf("--on flag is required on %s (e.g., --on root@167.71.50.23)\n\nbunkr server commands run on Linux. Use --on to target a remote server\n(or save one with 'bunkr server add --default'), or run bunkr directly on a\nLinux machine.", runtime.GOOS)
 	}
 	return nil
 }
@@ -3,11 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/pankajbeniwal/bunkr/internal/caddy"
 	"github.com/pankajbeniwal/bunkr/internal/docker"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
 	"github.com/pankajbeniwal/bunkr/internal/hardening"
+	"github.com/pankajbeniwal/bunkr/internal/preflight"
 	"github.com/pankajbeniwal/bunkr/internal/recipe"
 	"github.com/pankajbeniwal/bunkr/internal/state"
 	"github.com/pankajbeniwal/bunkr/internal/tailscale"
@@ -15,31 +19,64 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	dnsPollInterval = 15 * time.Second
+	dnsWaitTimeout  = 15 * time.Minute
+)
+
+var (
+	memoryFlag  string
+	cpusFlag    float64
+	pidsFlag    int
+	restartFlag string
+	setFlags    []string
+
+	skipPreflightFlag bool
+)
+
 var installCmd = &cobra.Command{
-	Use:   "install <recipe> [<recipe>...]",
+	Use:   "install <recipe>[@version] [<recipe>[@version]...]",
 	Short: "Harden server and install app(s)",
 	Args:  cobra.MinimumNArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		if err := requireRemote(); err != nil {
 			return err
 		}
 
 		ctx := context.Background()
 
+		overrides := recipe.Resources{Memory: memoryFlag, CPUs: cpusFlag, Pids: pidsFlag}
+		if err := overrides.Validate(); err != nil {
+			return err
+		}
+		if err := recipe.ValidateRestart(restartFlag); err != nil {
+			return err
+		}
+		settings, err := recipe.ParseSettings(setFlags)
+		if err != nil {
+			return err
+		}
+
 		// === PLAN PHASE (always local) ===
 
 		// Fetch and validate all recipes
 		type planned struct {
-			recipe *recipe.Recipe
-			values map[string]string
+			recipe    *recipe.Recipe
+			values    map[string]string
+			endpoints []state.EndpointState
 		}
 		var plans []planned
 
-		for _, name := range args {
-			ui.Header(fmt.Sprintf("Fetching %s...", name))
-			r, err := recipe.Fetch(name)
+		for _, arg := range args {
+			name, version := recipe.ParseRef(arg)
+			ui.Header(fmt.Sprintf("Fetching %s...", arg))
+			r, err := recipe.FetchVersion(name, version)
 			if err != nil {
-				return fmt.Errorf("failed to fetch recipe %s: %w", name, err)
+				return fmt.Errorf("failed to fetch recipe %s: %w", arg, err)
+			}
+			applyOverrides(r, limitsFromResources(overrides), restartFlag)
+			if err := recipe.ApplySettings(r, settings); err != nil {
+				return err
 			}
 
 			ui.Header(fmt.Sprintf("Configuring %s...", name))
@@ -66,25 +103,93 @@ var installCmd = &cobra.Command{
 			return err
 		}
 
-		// Set system-wide apt lock timeout (fresh VPS often has apt running)
-		exec.Run(ctx, `echo 'DPkg::Lock::Timeout "120";' > /etc/apt/apt.conf.d/99-bunkr-lock-wait`)
+		// Caddy requests certificates as soon as a site is added, and
+		// retries against a domain that isn't ours burn rate limits. DNS is
+		// checked even with --skip-preflight, and before locking the server
+		// since waiting for it can take minutes
+		dnsApps := make([]preflight.App, len(plans))
+		for i, p := range plans {
+			var endpoints []state.EndpointState
+			for _, e := range p.recipe.EndpointList() {
+				endpoints = append(endpoints, state.EndpointState{Name: e.Name, Domain: p.recipe.EndpointDomain(e, p.values)})
+			}
+			dnsApps[i] = preflight.App{Recipe: p.recipe, Endpoints: endpoints}
+		}
+		if err := checkDNS(ctx, exec, dnsApps); err != nil {
+			return err
+		}
 
-		s, err := state.Load(ctx, exec)
+		unlock, err := lockServer(ctx, exec)
 		if err != nil {
 			return err
 		}
+		defer unlock()
+
+		s, err := loadState(ctx, exec)
+		if err != nil {
+			return err
+		}
+
+		// Reserve host ports now, skipping any taken by sockets or compose
+		// files bunkr doesn't track
+		if err := scanPorts(ctx, exec, s); err != nil {
+			return err
+		}
+		for i := range plans {
+			plans[i].endpoints = allocateEndpoints(s, plans[i].recipe, plans[i].values, nil)
+		}
+
+		// Preflight (read-only, nothing has been changed yet)
+		if !skipPreflightFlag {
+			ui.Header("Running preflight checks...")
+			apps := make([]preflight.App, len(plans))
+			for i, p := range plans {
+				apps[i] = preflight.App{Recipe: p.recipe, Endpoints: p.endpoints}
+			}
+			results, err := preflight.Run(ctx, exec, s, apps)
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			for _, r := range results {
+				ui.CheckRow(r.Status.String(), r.App, r.Check, r.Detail)
+			}
+			if preflight.Failed(results) {
+				return fmt.Errorf("preflight checks failed, nothing was changed (use --skip-preflight to install anyway)")
+			}
+		}
+
+		names := make([]string, len(plans))
+		for i, p := range plans {
+			names[i] = p.recipe.Name
+		}
+		if err := takeSnapshot(ctx, exec, names...); err != nil {
+			return err
+		}
+		failing := ""
+		defer func() {
+			if err != nil {
+				recordFailure(ctx, exec, failing, err)
+			}
+		}()
+
+		// Set system-wide apt lock timeout (fresh VPS often has apt running)
+		exec.Run(ctx, `echo 'DPkg::Lock::Timeout "120";' > /etc/apt/apt.conf.d/99-bunkr-lock-wait`)
 
 		// Hardening
 		if !s.Hardening.Applied {
 			ui.Header("Hardening VPS...")
-			if _, err := hardening.Run(ctx, exec, s, sshPortFlag); err != nil {
+			results, err := hardening.Run(ctx, exec, s, sshPortFlag)
+			if err != nil {
 				return err
 			}
+			recordHardening(s, results)
 			s.Hardening.AppliedAt = time.Now()
 			s.Hardening.SSHPort = sshPortFlag
 
 			ui.Result("Server hardened successfully!")
 			ui.HardeningSummary(extractHost(onFlag), sshPortFlag)
+			rememberHardened(sshPortFlag)
 		}
 
 		// Docker
@@ -107,28 +212,9 @@ var installCmd = &cobra.Command{
 
 		// Tailscale (only if a private recipe is being installed)
 		if hasPrivate {
-			ui.Info("Checking Tailscale...")
-			if err := tailscale.EnsureInstalled(ctx, exec); err != nil {
+			if err := ensureTailscale(ctx, exec, s); err != nil {
 				return err
 			}
-
-			connected, _ := tailscale.IsConnected(ctx, exec)
-			if !connected {
-				hostname, err := tailscale.Connect(ctx, exec)
-				if err != nil {
-					return err
-				}
-				s.Tailscale.Hostname = hostname
-			} else if s.Tailscale.Hostname == "" {
-				hostname, err := tailscale.Hostname(ctx, exec)
-				if err != nil {
-					return err
-				}
-				s.Tailscale.Hostname = hostname
-			}
-			s.Tailscale.Installed = true
-			s.Tailscale.Connected = true
-			ui.Success("Tailscale ready")
 		}
 
 		// Caddy (only if a public recipe is being installed)
@@ -143,12 +229,15 @@ var installCmd = &cobra.Command{
 		// Install each recipe
 		for _, p := range plans {
 			r := p.recipe
+			failing = r.Name
 			ui.Header(fmt.Sprintf("Installing %s...", r.Name))
 
-			hostPort := s.AllocatePort(r.Ports[0])
+			// Keeps the reserved ports; fills in the Tailscale hostname for
+			// private apps now that it is known
+			endpoints := allocateEndpoints(s, r, p.values, p.endpoints)
 
 			// Generate files
-			composeData, err := recipe.GenerateCompose(r, p.values, hostPort)
+			composeData, err := recipe.GenerateCompose(r, p.values, hostPorts(endpoints)...)
 			if err != nil {
 				return err
 			}
@@ -170,20 +259,38 @@ var installCmd = &cobra.Command{
 				return err
 			}
 
-			// Network: Tailscale for private, Caddy for public
-			var domain string
-			if r.Private {
-				if err := tailscale.Serve(ctx, exec, hostPort); err != nil {
-					return err
-				}
-				domain = s.Tailscale.Hostname
-				ui.Success("Tailscale serve configured")
-			} else {
-				domain = p.values["DOMAIN"]
-				if err := caddy.AddBlock(ctx, exec, r.Name, domain, hostPort); err != nil {
-					return err
-				}
-				ui.Success("Caddy configured")
+			files, err := recipe.RenderFiles(r, p.values)
+			if err != nil {
+				return err
+			}
+			if err := writeAppFiles(ctx, exec, dir, files); err != nil {
+				return err
+			}
+			if len(files) > 0 {
+				ui.Success("Config files written")
+			}
+
+			// Network: Tailscale for private, Caddy and firewall for public
+			if err := exposeEndpoints(ctx, exec, r.Name, r.Private, endpoints); err != nil {
+				return err
+			}
+
+			// Record the app before any container starts, so its ports are
+			// known to later installs even if this one stops here
+			s.Recipes[r.Name] = state.RecipeState{
+				Version:       r.Version,
+				Domain:        endpoints[0].Domain,
+				Private:       r.Private,
+				InstalledAt:   time.Now(),
+				Port:          endpoints[0].Port,
+				ContainerPort: endpoints[0].ContainerPort,
+				Endpoints:     endpoints,
+				Limits:        limitsFromResources(overrides),
+				Restart:       restartFlag,
+				Settings:      settings,
+			}
+			if err := state.Save(ctx, exec, s); err != nil {
+				return err
 			}
 
 			// Run init command (e.g. "openclaw setup") before starting
@@ -221,15 +328,7 @@ var installCmd = &cobra.Command{
 				}
 			}
 
-			// Update state
-			s.Recipes[r.Name] = state.RecipeState{
-				Version:       r.Version,
-				Domain:        domain,
-				Private:       r.Private,
-				InstalledAt:   time.Now(),
-				Port:          hostPort,
-				ContainerPort: r.Ports[0],
-			}
+			s.Record(newEvent(r.Name, state.EventInstalled, "version "+r.Version))
 		}
 
 		// Reload Caddy once (only if public recipes were installed)
@@ -239,15 +338,23 @@ var installCmd = &cobra.Command{
 			}
 		}
 
-		// Save state
+		failing = ""
 		if err := state.Save(ctx, exec, s); err != nil {
 			return err
 		}
+		rememberApps(serverName, s)
 
 		// Print results
 		for _, p := range plans {
 			rs := s.Recipes[p.recipe.Name]
-			ui.Result(fmt.Sprintf("%s is running at https://%s", p.recipe.Name, rs.Domain))
+			host := extractHost(onFlag)
+			if rs.Private {
+				host = s.Tailscale.Hostname
+			}
+			ui.Result(fmt.Sprintf("%s is running at %s", p.recipe.Name, endpointAddress(rs.Endpoints[0], host)))
+			for _, ep := range rs.Endpoints[1:] {
+				ui.Info(fmt.Sprintf("  %s: %s", ep.Name, endpointAddress(ep, host)))
+			}
 
 			// Show auto-generated secrets the user needs to save
 			if len(p.recipe.Display) > 0 {
@@ -267,6 +374,144 @@ var installCmd = &cobra.Command{
 	},
 }
 
+// ensureTailscale installs and connects Tailscale if needed, recording the
+// server's tailnet hostname in s.
+func ensureTailscale(ctx context.Context, exec executor.Executor, s *state.State) error {
+	ui.Info("Checking Tailscale...")
+	if err := tailscale.EnsureInstalled(ctx, exec); err != nil {
+		return err
+	}
+
+	connected, _ := tailscale.IsConnected(ctx, exec)
+	if !connected {
+		hostname, err := tailscale.Connect(ctx, exec)
+		if err != nil {
+			return err
+		}
+		s.Tailscale.Hostname = hostname
+	} else if s.Tailscale.Hostname == "" {
+		hostname, err := tailscale.Hostname(ctx, exec)
+		if err != nil {
+			return err
+		}
+		s.Tailscale.Hostname = hostname
+	}
+	s.Tailscale.Installed = true
+	s.Tailscale.Connected = true
+	ui.Success("Tailscale ready")
+	return nil
+}
+
+// checkDNS looks up the apps' public domains and, when any doesn't point at
+// the server yet, asks whether to wait for it.
+func checkDNS(ctx context.Context, exec executor.Executor, apps []preflight.App) error {
+	results := preflight.CheckDomains(ctx, exec, net.DefaultResolver, apps)
+	if len(results) == 0 {
+		return nil
+	}
+	ui.Header("Checking DNS...")
+	for _, r := range results {
+		ui.CheckRow(r.Status.String(), r.App, r.Check, r.Detail)
+	}
+	if pending := preflight.PendingDNS(results); len(pending) > 0 {
+		return confirmDNS(ctx, exec, net.DefaultResolver, pending)
+	}
+	return nil
+}
+
+// confirmDNS asks whether to wait for domains to point at the server, polling
+// until they do, or to continue regardless.
+func confirmDNS(ctx context.Context, exec executor.Executor, resolver preflight.Resolver, domains []string) error {
+	ui.Warn("DNS for " + strings.Join(domains, ", ") + " does not point at this server yet")
+	answer, err := recipe.PromptUser([]recipe.Prompt{{
+		Key:     "dns",
+		Label:   "How do you want to proceed?",
+		Options: []string{"wait", "continue", "abort"},
+		Default: "wait",
+	}})
+	if err != nil {
+		return err
+	}
+	switch answer["dns"] {
+	case "continue":
+		ui.Warn("Continuing — certificates will be issued once DNS propagates")
+		return nil
+	case "abort":
+		return fmt.Errorf("aborted, nothing was changed")
+	}
+
+	server, err := preflight.PublicIPs(ctx, exec)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(dnsWaitTimeout)
+	for len(domains) > 0 {
+		var waiting []string
+		for _, domain := range domains {
+			res := preflight.CheckDNS(ctx, resolver, "", domain, server)
+			if res.Status == preflight.Pass {
+				ui.Success(fmt.Sprintf("DNS for %s %s", domain, res.Detail))
+			} else {
+				waiting = append(waiting, domain)
+			}
+		}
+		domains = waiting
+		if len(domains) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("DNS for %s still does not point at this server after %s, nothing was changed", strings.Join(domains, ", "), dnsWaitTimeout)
+		}
+		ui.Info(fmt.Sprintf("Waiting for DNS (%s)...", strings.Join(domains, ", ")))
+		time.Sleep(dnsPollInterval)
+	}
+	return nil
+}
+
+// applyOverrides layers user-supplied limits and restart policy on top of the
+// recipe defaults for the primary service.
+func applyOverrides(r *recipe.Recipe, limits *state.ResourceLimits, restart string) {
+	if limits != nil {
+		r.Resources = r.Resources.Merge(recipe.Resources{
+			Memory: limits.Memory,
+			CPUs:   limits.CPUs,
+			Pids:   limits.Pids,
+		})
+	}
+	if restart != "" {
+		r.Restart = restart
+	}
+}
+
+// writeAppFiles writes rendered recipe files into the app directory.
+func writeAppFiles(ctx context.Context, exec executor.Executor, dir string, files []recipe.RenderedFile) error {
+	for _, f := range files {
+		path := dir + "/" + f.Path
+		if err := exec.WriteFile(ctx, path, f.Content, f.Mode); err != nil {
+			return err
+		}
+		if f.Owner != "" {
+			if _, err := exec.Run(ctx, fmt.Sprintf("chown %s %s", f.Owner, path)); err != nil {
+				return fmt.Errorf("failed to set owner of %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func limitsFromResources(res recipe.Resources) *state.ResourceLimits {
+	if res.IsZero() {
+		return nil
+	}
+	return &state.ResourceLimits{Memory: res.Memory, CPUs: res.CPUs, Pids: res.Pids}
+}
+
 func init() {
+	installCmd.Flags().StringVar(&memoryFlag, "memory", "", "memory limit for the app container (e.g. 512m, 1g)")
+	installCmd.Flags().Float64Var(&cpusFlag, "cpus", 0, "CPU limit for the app container (e.g. 0.5)")
+	installCmd.Flags().IntVar(&pidsFlag, "pids-limit", 0, "maximum number of processes in the app container")
+	installCmd.Flags().StringArrayVar(&setFlags, "set", nil, "override a recipe setting, e.g. --set logging.max_size=50m (repeatable)")
+	installCmd.Flags().BoolVar(&skipPreflightFlag, "skip-preflight", false, "skip the server checks that run before anything is installed (DNS is still checked)")
+	installCmd.Flags().StringVar(&restartFlag, "restart", "", "restart policy for the app container (no, always, on-failure, unless-stopped)")
 	rootCmd.AddCommand(installCmd)
 }
@@ -8,6 +8,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var tagFlag string
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available recipes",
@@ -19,17 +21,39 @@ var listCmd = &cobra.Command{
 			return err
 		}
 
-		fmt.Printf("\n  %-20s %-10s %s\n", "NAME", "VERSION", "DESCRIPTION")
-		fmt.Printf("  %-20s %-10s %s\n", "----", "-------", "-----------")
-		for _, entry := range index {
-			fmt.Printf("  %-20s %-10s %s\n", entry.Name, entry.Version, entry.Description)
+		if tagFlag != "" {
+			var tagged []recipe.IndexEntry
+			for _, entry := range index {
+				if entry.HasTag(tagFlag) {
+					tagged = append(tagged, entry)
+				}
+			}
+			if len(tagged) == 0 {
+				ui.Info(fmt.Sprintf("No recipes tagged %q", tagFlag))
+				return nil
+			}
+			index = tagged
 		}
-		fmt.Println()
 
+		printIndex(index)
 		return nil
 	},
 }
 
+func printIndex(index []recipe.IndexEntry) {
+	fmt.Printf("\n  %-20s %-10s %-14s %s\n", "NAME", "VERSION", "CATEGORY", "DESCRIPTION")
+	fmt.Printf("  %-20s %-10s %-14s %s\n", "----", "-------", "--------", "-----------")
+	for _, entry := range index {
+		category := entry.Category
+		if category == "" {
+			category = "-"
+		}
+		fmt.Printf("  %-20s %-10s %-14s %s\n", entry.Name, entry.Version, category, entry.Description)
+	}
+	fmt.Println()
+}
+
 func init() {
+	listCmd.Flags().StringVar(&tagFlag, "tag", "", "only show recipes with this tag or category")
 	rootCmd.AddCommand(listCmd)
 }
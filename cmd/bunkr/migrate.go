@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/docker"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/preflight"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFromFlag string
+	migrateToFlag   string
+	stopSourceFlag  bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <app> --from <server> --to <server>",
+	Short: "Move an app with its data and config to another server",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		name := args[0]
+		if migrateFromFlag == "" || migrateToFlag == "" {
+			return fmt.Errorf("both --from and --to are required (server names or user@host[:port])")
+		}
+		fromTarget, fromName, err := lookupServer(migrateFromFlag)
+		if err != nil {
+			return err
+		}
+		toTarget, toName, err := lookupServer(migrateToFlag)
+		if err != nil {
+			return err
+		}
+		fromLabel, toLabel := serverLabel(fromTarget, fromName), serverLabel(toTarget, toName)
+		if fromLabel == toLabel {
+			return fmt.Errorf("--from and --to are the same server")
+		}
+
+		ctx := context.Background()
+		src, err := executor.NewRemoteExecutor(fromTarget)
+		if err != nil {
+			return err
+		}
+		dst, err := executor.NewRemoteExecutor(toTarget)
+		if err != nil {
+			return err
+		}
+
+		// Caddy on the target requests certificates as soon as the app's
+		// sites are added, so DNS is checked first, before locking either
+		// server since waiting for it can take minutes
+		if peek, err := loadState(ctx, src); err == nil {
+			if app, ok := peek.Recipes[name]; ok {
+				apps := []preflight.App{{Recipe: &recipe.Recipe{Name: name, Private: app.Private}, Endpoints: app.EndpointList()}}
+				if err := checkDNS(ctx, dst, apps); err != nil {
+					return err
+				}
+			}
+		}
+
+		unlockSrc, err := lockServer(ctx, src)
+		if err != nil {
+			return err
+		}
+		defer unlockSrc()
+		unlockDst, err := lockServer(ctx, dst)
+		if err != nil {
+			return err
+		}
+		defer unlockDst()
+
+		srcState, err := loadState(ctx, src)
+		if err != nil {
+			return err
+		}
+		app, ok := srcState.Recipes[name]
+		if !ok {
+			return fmt.Errorf("recipe %s is not installed on %s", name, fromLabel)
+		}
+		dstState, err := loadState(ctx, dst)
+		if err != nil {
+			return err
+		}
+		if _, ok := dstState.Recipes[name]; ok {
+			return fmt.Errorf("recipe %s is already installed on %s", name, toLabel)
+		}
+		if !dstState.Hardening.Applied {
+			return fmt.Errorf("%s isn't hardened yet, run 'bunkr init --on %s' first", toLabel, migrateToFlag)
+		}
+
+		// The recipe supplies the health check, and regenerates the compose
+		// file if the app's ports are taken on the target
+		r, rerr := recipe.FetchVersion(name, app.Version)
+		if rerr != nil {
+			ui.Warn(fmt.Sprintf("Couldn't fetch recipe %s@%s, only container state will be checked: %s", name, app.Version, rerr))
+			r = nil
+		}
+
+		if err := scanPorts(ctx, dst, dstState); err != nil {
+			return err
+		}
+		var endpoints []state.EndpointState
+		moved := false
+		for _, ep := range app.EndpointList() {
+			port := dstState.AllocatePort(ep.Port)
+			if port != ep.Port {
+				ui.Info(fmt.Sprintf("Port %d is in use on %s, %s will use %d", ep.Port, toLabel, ep.Name, port))
+				moved = true
+			}
+			ep.Port = port
+			endpoints = append(endpoints, ep)
+		}
+		if moved && r == nil {
+			return fmt.Errorf("ports must change on %s, which needs recipe %s@%s to regenerate the compose file", toLabel, name, app.Version)
+		}
+
+		ui.Header(fmt.Sprintf("Preparing %s...", toLabel))
+		if err := docker.EnsureInstalled(ctx, dst); err != nil {
+			return err
+		}
+		ui.Success("Docker ready")
+		if app.Private {
+			if err := ensureTailscale(ctx, dst, dstState); err != nil {
+				return err
+			}
+			for i := range endpoints {
+				if endpoints[i].Protocol != recipe.ProtocolTCP {
+					endpoints[i].Domain = dstState.Tailscale.Hostname
+				}
+			}
+		} else {
+			if err := caddy.EnsureInstalled(ctx, dst); err != nil {
+				return err
+			}
+			ui.Success("Caddy ready")
+		}
+
+		if err := takeSnapshot(ctx, src, name); err != nil {
+			return err
+		}
+		if err := takeSnapshot(ctx, dst, name); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				recordFailure(ctx, dst, name, err)
+			}
+		}()
+
+		// Copy with the app stopped so databases are consistent. The source
+		// is started again if anything below fails
+		ui.Header(fmt.Sprintf("Copying %s from %s...", name, fromLabel))
+		volumes, err := docker.ComposeVolumes(ctx, src, name)
+		if err != nil {
+			return err
+		}
+		if err := docker.ComposeDown(ctx, src, name, false); err != nil {
+			return fmt.Errorf("failed to stop %s on %s: %w", name, fromLabel, err)
+		}
+		sourceStopped := true
+		defer func() {
+			if err == nil || !sourceStopped {
+				return
+			}
+			if uerr := docker.ComposeUp(ctx, src, name); uerr != nil {
+				ui.Warn(fmt.Sprintf("Failed to restart %s on %s: %s", name, fromLabel, uerr))
+			} else {
+				ui.Info(fmt.Sprintf("%s restarted on %s", name, fromLabel))
+			}
+		}()
+
+		if err := executor.Pipe(
+			func(w io.Writer) error { return docker.ExportAppDir(ctx, src, name, w) },
+			func(in io.Reader) error { return docker.ImportAppDir(ctx, dst, name, in) },
+		); err != nil {
+			return err
+		}
+		ui.Success(fmt.Sprintf("Copied /opt/bunkr/%s", name))
+		for _, v := range volumes {
+			if err := executor.Pipe(
+				func(w io.Writer) error { return docker.ExportVolume(ctx, src, v, w) },
+				func(in io.Reader) error { return docker.ImportVolume(ctx, dst, name, v, in) },
+			); err != nil {
+				return err
+			}
+			ui.Success(fmt.Sprintf("Copied volume %s", v.Name))
+		}
+
+		if !stopSourceFlag {
+			if err := docker.ComposeUp(ctx, src, name); err != nil {
+				return fmt.Errorf("failed to restart %s on %s: %w", name, fromLabel, err)
+			}
+			sourceStopped = false
+			ui.Success(fmt.Sprintf("%s restarted on %s", name, fromLabel))
+		}
+
+		dir := fmt.Sprintf("/opt/bunkr/%s", name)
+		if moved {
+			applyOverrides(r, app.Limits, app.Restart)
+			if err := recipe.ApplySettings(r, app.Settings); err != nil {
+				return err
+			}
+			env, err := dst.ReadFile(ctx, dir+"/.env")
+			if err != nil {
+				return fmt.Errorf("failed to read copied values: %w", err)
+			}
+			answers, _ := recipe.ReuseValues(r, recipe.ParseEnv(env))
+			composeData, err := recipe.GenerateCompose(r, recipe.MergeValues(answers, r.Environment), hostPorts(endpoints)...)
+			if err != nil {
+				return err
+			}
+			if err := dst.WriteFile(ctx, dir+"/docker-compose.yml", composeData, 0644); err != nil {
+				return err
+			}
+			ui.Success("Compose file regenerated for the new ports")
+		}
+
+		// Record the app before its containers start, as install does
+		migrated := app
+		migrated.Endpoints = endpoints
+		migrated.Domain = endpoints[0].Domain
+		migrated.Port = endpoints[0].Port
+		migrated.ContainerPort = endpoints[0].ContainerPort
+		dstState.Recipes[name] = migrated
+		if err := state.Save(ctx, dst, dstState); err != nil {
+			return err
+		}
+
+		// Caddy is reloaded once the containers are up, so it never proxies
+		// to ports with nothing behind them
+		ui.Header(fmt.Sprintf("Starting %s on %s...", name, toLabel))
+		if err := exposeEndpoints(ctx, dst, name, app.Private, endpoints); err != nil {
+			return err
+		}
+		if err := docker.ComposeUp(ctx, dst, name); err != nil {
+			return err
+		}
+		if !app.Private {
+			if err := caddy.Reload(ctx, dst); err != nil {
+				ui.Warn("Caddy reload failed — you may need to run 'caddy reload' manually")
+			}
+		}
+		if err := verifyRunning(ctx, dst, name, r, endpoints); err != nil {
+			return fmt.Errorf("%s started on %s but isn't healthy: %w", name, toLabel, err)
+		}
+		ui.Success("Containers running and healthy")
+
+		dstState.Record(newEvent(name, state.EventMigrated, "from "+fromLabel))
+		if err := state.Save(ctx, dst, dstState); err != nil {
+			return err
+		}
+		rememberApps(toName, dstState)
+
+		// Only now is the source safe to retire
+		if stopSourceFlag {
+			sourceStopped = false
+			unexposeEndpoints(ctx, src, name, app.Private, app.EndpointList())
+			if !app.Private {
+				if err := caddy.Reload(ctx, src); err != nil {
+					ui.Warn(fmt.Sprintf("Caddy reload failed on %s — you may need to run 'caddy reload' manually", fromLabel))
+				}
+			}
+			delete(srcState.Recipes, name)
+			srcState.Record(newEvent(name, state.EventMigrated, "to "+toLabel+", stopped here"))
+		} else {
+			srcState.Record(newEvent(name, state.EventMigrated, "copied to "+toLabel))
+		}
+		if err := state.Save(ctx, src, srcState); err != nil {
+			return err
+		}
+		rememberApps(fromName, srcState)
+
+		host := extractHost(toTarget)
+		if app.Private {
+			host = dstState.Tailscale.Hostname
+		}
+		ui.Result(fmt.Sprintf("%s is running on %s at %s", name, toLabel, endpointAddress(endpoints[0], host)))
+		if domains := publicDomains(migrated); len(domains) > 0 {
+			ui.Info(fmt.Sprintf("Point DNS for %s at %s; Caddy gets certificates once it does", strings.Join(domains, ", "), extractHost(toTarget)))
+		}
+		if stopSourceFlag {
+			// bunkr no longer tracks these on the source
+			leftovers := []string{dir}
+			cleanup := "rm -rf " + dir
+			if len(volumes) > 0 {
+				var names []string
+				for _, v := range volumes {
+					names = append(names, v.Name)
+				}
+				leftovers = append(leftovers, "volumes "+strings.Join(names, ", "))
+				cleanup = "docker volume rm " + strings.Join(names, " ") + " && " + cleanup
+			}
+			ui.Warn(fmt.Sprintf("%s is no longer managed on %s, but its data is still there: %s", name, fromLabel, strings.Join(leftovers, " and ")))
+			ui.Info(fmt.Sprintf("Once you're happy with %s on %s, remove it with: %s", name, toLabel, cleanup))
+		} else {
+			ui.Info(fmt.Sprintf("%s is still running on %s; stop it with 'bunkr uninstall %s --on %s'", name, fromLabel, name, migrateFromFlag))
+		}
+		return nil
+	},
+}
+
+// verifyRunning checks that every container of the app is running and, when
+// the recipe has one, that its health check passes on the app's host ports.
+func verifyRunning(ctx context.Context, exec executor.Executor, name string, r *recipe.Recipe, endpoints []state.EndpointState) error {
+	statuses, err := docker.ComposeStatus(ctx, exec, name)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		return fmt.Errorf("no containers are running")
+	}
+	for _, st := range statuses {
+		if st.Status != "running" {
+			return fmt.Errorf("%s is %s", st.Name, st.Status)
+		}
+	}
+	if r != nil && r.HealthCheck != nil {
+		return docker.HealthCheck(ctx, exec, healthCheckURL(r, endpoints), r.HealthCheck.Timeout, r.HealthCheck.Interval)
+	}
+	return nil
+}
+
+// healthCheckURL returns the recipe's health check URL with the port of the
+// endpoint it targets replaced by the host port that endpoint was given.
+func healthCheckURL(r *recipe.Recipe, endpoints []state.EndpointState) string {
+	raw := r.HealthCheck.URL
+	u, err := url.Parse(raw)
+	if err != nil || u.Port() == "" {
+		return raw
+	}
+	for _, e := range r.EndpointList() {
+		if strconv.Itoa(e.DesiredHostPort()) != u.Port() && strconv.Itoa(e.Port) != u.Port() {
+			continue
+		}
+		for _, ep := range endpoints {
+			if ep.Name == e.Name {
+				u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(ep.Port))
+				return u.String()
+			}
+		}
+	}
+	return raw
+}
+
+func publicDomains(app state.RecipeState) []string {
+	if app.Private {
+		return nil
+	}
+	var domains []string
+	for _, ep := range app.EndpointList() {
+		if ep.Domain != "" && !slices.Contains(domains, ep.Domain) {
+			domains = append(domains, ep.Domain)
+		}
+	}
+	return domains
+}
+
+// serverLabel names a server in messages: its inventory name, else its host.
+func serverLabel(target, name string) string {
+	if name != "" {
+		return name
+	}
+	return extractHost(target)
+}
+
+func init() {
+	migrateCmd.Flags().StringVar(&migrateFromFlag, "from", "", "server the app runs on now (name or user@host[:port])")
+	migrateCmd.Flags().StringVar(&migrateToFlag, "to", "", "server to move the app to (name or user@host[:port])")
+	migrateCmd.Flags().BoolVar(&stopSourceFlag, "stop-source", false, "once the app is healthy on the target, stop it on the source and remove it from the source's state and routing; its directory and volumes are left there for you to remove")
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var recipeCmd = &cobra.Command{
+	Use:   "recipe",
+	Short: "Tools for writing and maintaining recipes",
+}
+
+var recipeLintCmd = &cobra.Command{
+	Use:   "lint <file|recipe> [<file|recipe>...]",
+	Short: "Check recipes for invalid or weak security settings",
+	Args:  cobra.MinimumNArgs(1),
+	// Lint findings are not usage errors
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		warnings := 0
+		for _, arg := range args {
+			ui.Header(fmt.Sprintf("Linting %s...", arg))
+
+			r, err := loadRecipeArg(arg)
+			if err != nil {
+				ui.Error(err.Error())
+				warnings++
+				continue
+			}
+
+			issues := recipe.Lint(r)
+			if len(issues) == 0 {
+				ui.Success("No issues found")
+				continue
+			}
+			for _, issue := range issues {
+				if issue.Level == recipe.LintWarn {
+					ui.Warn(issue.String())
+					warnings++
+				} else {
+					ui.Info("  " + issue.String())
+				}
+			}
+		}
+		fmt.Println()
+
+		if warnings > 0 {
+			return fmt.Errorf("found %d problem(s)", warnings)
+		}
+		return nil
+	},
+}
+
+var indexCheckFlag bool
+
+var recipeIndexCmd = &cobra.Command{
+	Use:   "index <dir>",
+	Short: "Generate index.yaml from a directory of recipes",
+	Args:  cobra.ExactArgs(1),
+	// A stale index is not a usage error
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := args[0]
+		index, err := recipe.BuildIndex(dir)
+		if err != nil {
+			return err
+		}
+		data, err := recipe.MarshalIndex(index)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, "index.yaml")
+		if indexCheckFlag {
+			existing, err := os.ReadFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if !bytes.Equal(existing, data) {
+				return fmt.Errorf("%s is out of date, run: bunkr recipe index %s", path, dir)
+			}
+			ui.Success(fmt.Sprintf("%s is up to date (%d recipes)", path, len(index)))
+			return nil
+		}
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		ui.Success(fmt.Sprintf("Wrote %s (%d recipes)", path, len(index)))
+		return nil
+	},
+}
+
+var (
+	importPrimaryFlag string
+	importNameFlag    string
+	importOutputFlag  string
+)
+
+var recipeImportCmd = &cobra.Command{
+	Use:   "import <docker-compose.yml>",
+	Short: "Convert a docker-compose file into a recipe",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		ui.Header(fmt.Sprintf("Importing %s...", args[0]))
+		r, issues, err := recipe.Import(data, recipe.ImportOptions{
+			Name:    importNameFlag,
+			Primary: importPrimaryFlag,
+			Source:  args[0],
+		})
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			if issue.Level == recipe.LintWarn {
+				ui.Warn(issue.String())
+			} else {
+				ui.Info("  " + issue.String())
+			}
+		}
+
+		out, err := recipe.MarshalRecipe(r)
+		if err != nil {
+			return err
+		}
+		path := importOutputFlag
+		if path == "" {
+			path = r.Name + ".yaml"
+		}
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists, choose another file with --output", path)
+		}
+		if err := os.WriteFile(path, out, 0644); err != nil {
+			return err
+		}
+
+		ui.Result(fmt.Sprintf("Wrote %s — review it, then run: bunkr recipe lint %s", path, path))
+		return nil
+	},
+}
+
+// loadRecipeArg reads a recipe from a local file if one exists at arg,
+// otherwise fetches it by name from the recipe repository.
+func loadRecipeArg(arg string) (*recipe.Recipe, error) {
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return recipe.Fetch(arg)
+		}
+		return nil, err
+	}
+	r, err := recipe.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid recipe %s: %w", arg, err)
+	}
+	return r, nil
+}
+
+func init() {
+	recipeIndexCmd.Flags().BoolVar(&indexCheckFlag, "check", false, "fail if index.yaml differs from the generated index instead of writing it")
+	recipeCmd.AddCommand(recipeLintCmd)
+	recipeCmd.AddCommand(recipeIndexCmd)
+	recipeImportCmd.Flags().StringVar(&importPrimaryFlag, "primary", "", "service to use as the app (picked automatically by default)")
+	recipeImportCmd.Flags().StringVar(&importNameFlag, "name", "", "recipe name (default: the primary service's name)")
+	recipeImportCmd.Flags().StringVarP(&importOutputFlag, "output", "o", "", "file to write (default: <name>.yaml)")
+	recipeCmd.AddCommand(recipeImportCmd)
+	rootCmd.AddCommand(recipeCmd)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/docker"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/snapshot"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var listSnapshotsFlag bool
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [snapshot]",
+	Short: "Restore state, Caddy and app config from a snapshot (latest by default)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if err := requireRemote(); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		exec, err := newExecutor()
+		if err != nil {
+			return err
+		}
+
+		if listSnapshotsFlag {
+			return printSnapshots(ctx, exec)
+		}
+
+		unlock, err := lockServer(ctx, exec)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		id := ""
+		if len(args) > 0 {
+			id = args[0]
+		}
+		target, err := snapshot.Find(ctx, exec, id)
+		if err != nil {
+			return err
+		}
+
+		// A corrupt state file is one of the things a rollback fixes, so
+		// only a failed read stops us here
+		current, err := state.Load(ctx, exec)
+		if err != nil && !errors.Is(err, state.ErrCorrupt) {
+			return err
+		}
+		if current == nil {
+			current = state.New()
+		}
+
+		ui.Header(fmt.Sprintf("Rolling back to %s (before '%s')...", target.ID, target.Command))
+
+		// Snapshot what we're replacing so the rollback itself can be undone.
+		// Apps installed since the snapshot are in current but not target
+		apps := append([]string(nil), target.Apps...)
+		for name := range current.Recipes {
+			apps = append(apps, name)
+		}
+		slices.Sort(apps)
+		apps = slices.Compact(apps)
+		undo, err := snapshot.Take(ctx, exec, commandLine(), apps)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				recordFailure(ctx, exec, "", err)
+			}
+		}()
+
+		absent, err := snapshot.Restore(ctx, exec, target)
+		if err != nil {
+			return err
+		}
+		ui.Success("Files restored")
+
+		// History is append-only, so it survives the rollback
+		restored, err := state.Load(ctx, exec)
+		if err != nil {
+			return err
+		}
+		restored.Events = current.Events
+		if err := state.Save(ctx, exec, restored); err != nil {
+			return err
+		}
+		rememberApps(serverName, restored)
+		for _, name := range apps {
+			before, had := current.Recipes[name]
+			after, has := restored.Recipes[name]
+
+			// Installed after the snapshot was taken
+			if slices.Contains(absent, name) || !has {
+				if err := docker.ComposeDown(ctx, exec, name, false); err != nil {
+					ui.Warn(fmt.Sprintf("Failed to stop %s: %s", name, err))
+				} else {
+					ui.Success(fmt.Sprintf("%s stopped, its data is left in /opt/bunkr/%s", name, name))
+				}
+				if had {
+					unexposeEndpoints(ctx, exec, name, before.Private, before.EndpointList())
+				}
+				restored.Record(newEvent(name, state.EventRolledBack, "stopped, not installed as of "+target.ID))
+				continue
+			}
+
+			// Files outside the snapshot weren't touched, so only routing
+			// may need re-applying
+			if slices.Contains(target.Apps, name) {
+				if err := docker.ComposeUp(ctx, exec, name); err != nil {
+					return fmt.Errorf("failed to restart %s: %w", name, err)
+				}
+				ui.Success(fmt.Sprintf("%s restarted", name))
+				restored.Record(newEvent(name, state.EventRolledBack, fmt.Sprintf("to %s as of %s", after.Version, target.ID)))
+			}
+
+			// The Caddyfile is already restored; Tailscale Serve and
+			// firewall rules are re-applied when the routing differs
+			if had && before.Private == after.Private && !endpointsChanged(before.EndpointList(), after.EndpointList()) {
+				continue
+			}
+			if had {
+				unexposeEndpoints(ctx, exec, name, before.Private, before.EndpointList())
+			}
+			if err := exposeEndpoints(ctx, exec, name, after.Private, after.EndpointList()); err != nil {
+				return err
+			}
+		}
+
+		if err := caddy.Reload(ctx, exec); err != nil {
+			ui.Warn("Caddy reload failed — you may need to run 'caddy reload' manually")
+		}
+
+		restored.Record(newEvent("", state.EventRolledBack, "to "+target.ID))
+		if err := state.Save(ctx, exec, restored); err != nil {
+			return err
+		}
+
+		if err := snapshot.Prune(ctx, exec, snapshot.Keep); err != nil {
+			ui.Warn(err.Error())
+		}
+
+		ui.Result(fmt.Sprintf("Rolled back to %s", target.ID))
+		ui.Info(fmt.Sprintf("To undo, run 'bunkr rollback %s'", undo.ID))
+		return nil
+	},
+}
+
+// takeSnapshot saves the server's state, Caddyfile and the given apps'
+// compose and .env files before a command changes them.
+func takeSnapshot(ctx context.Context, exec executor.Executor, apps ...string) error {
+	if _, err := snapshot.Take(ctx, exec, commandLine(), apps); err != nil {
+		return fmt.Errorf("%w (nothing was changed)", err)
+	}
+	if err := snapshot.Prune(ctx, exec, snapshot.Keep); err != nil {
+		ui.Warn(err.Error())
+	}
+	return nil
+}
+
+func printSnapshots(ctx context.Context, exec executor.Executor) error {
+	snapshots, err := snapshot.List(ctx, exec)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		ui.Info("No snapshots yet")
+		return nil
+	}
+	fmt.Printf("  %-22s %-20s %-20s %s\n", "SNAPSHOT", "TAKEN", "APPS", "BEFORE")
+	for _, s := range snapshots {
+		apps := strings.Join(s.Apps, ",")
+		if apps == "" {
+			apps = "-"
+		}
+		fmt.Printf("  %-22s %-20s %-20s %s\n", s.ID, s.Created.Local().Format("2006-01-02 15:04:05"), apps, s.Command)
+	}
+	return nil
+}
+
+func init() {
+	rollbackCmd.Flags().BoolVar(&listSnapshotsFlag, "list", false, "list snapshots instead of restoring one")
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -1,12 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
 
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
 	"github.com/spf13/cobra"
 )
 
-var onFlag string
+var (
+	onFlag          string
+	resetStateFlag  bool
+	lockTimeoutFlag time.Duration
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "bunkr",
@@ -15,7 +28,9 @@ var rootCmd = &cobra.Command{
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&onFlag, "on", "", "remote server to execute on (e.g., root@167.71.50.23)")
+	rootCmd.PersistentFlags().StringVar(&onFlag, "on", "", "remote server to execute on, as user@host[:port] or a name from 'bunkr server list'")
+	rootCmd.PersistentFlags().BoolVar(&resetStateFlag, "reset-state", false, "move a corrupt state file aside and start from an empty state")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeoutFlag, "lock-timeout", 2*time.Minute, "how long to wait for another bunkr command to release the server")
 	rootCmd.AddCommand(versionCmd)
 }
 
@@ -26,3 +41,40 @@ var versionCmd = &cobra.Command{
 		fmt.Println("bunkr", version)
 	},
 }
+
+// commandLine is how the running command is described in the server lock
+// and snapshots.
+func commandLine() string {
+	return "bunkr " + strings.Join(os.Args[1:], " ")
+}
+
+// operator returns the local user and host running bunkr, for the server
+// lock and the event history.
+func operator() (username, host string) {
+	username, host = "unknown", "unknown"
+	if h, err := os.Hostname(); err == nil {
+		host = h
+	}
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	return username, host
+}
+
+// loadState loads the server's state, refusing to go on with a corrupt
+// state file unless --reset-state was given.
+func loadState(ctx context.Context, exec executor.Executor) (*state.State, error) {
+	s, err := state.Load(ctx, exec)
+	if errors.Is(err, state.ErrCorrupt) {
+		if !resetStateFlag {
+			return nil, fmt.Errorf("%w\n\nbunkr won't guess at what's installed. Fix %s by hand, or pass --reset-state\nto move it aside and start from an empty state.", err, state.StatePath)
+		}
+		s, backup, err := state.Reset(ctx, exec)
+		if err != nil {
+			return nil, err
+		}
+		ui.Warn(fmt.Sprintf("Corrupt state moved to %s, starting from an empty state", backup))
+		return s, nil
+	}
+	return s, err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <term>",
+	Short: "Search recipes by name, description, category or tag",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		term := strings.Join(args, " ")
+		ui.Header(fmt.Sprintf("Recipes matching %q", term))
+
+		index, err := recipe.FetchIndex()
+		if err != nil {
+			return err
+		}
+
+		var matches []recipe.IndexEntry
+		for _, entry := range index {
+			if entry.Matches(term) {
+				matches = append(matches, entry)
+			}
+		}
+		if len(matches) == 0 {
+			ui.Info("No recipes found")
+			return nil
+		}
+
+		printIndex(matches)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/inventory"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// serverName is the inventory entry --on resolved to, "" for a target that
+// isn't in the inventory or when running locally.
+var serverName string
+
+var serverDefaultFlag bool
+
+var serverCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Manage the local inventory of servers used by --on",
+}
+
+var serverAddCmd = &cobra.Command{
+	Use:   "add <name> <user@host[:port]>",
+	Short: "Save a server under a name for --on",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name == "" || strings.ContainsAny(name, "@:/ ") {
+			return fmt.Errorf("invalid server name %q, use letters, digits and dashes", name)
+		}
+		srv, err := inventory.ParseTarget(args[1])
+		if err != nil {
+			return err
+		}
+		return editInventory(func(inv *inventory.Inventory) error {
+			if old, ok := inv.Servers[name]; ok {
+				srv.Apps = old.Apps
+			}
+			inv.Servers[name] = &srv
+			if serverDefaultFlag {
+				inv.Default = name
+			}
+			ui.Success(fmt.Sprintf("Saved %s as %s", srv.Target(), name))
+			return nil
+		})
+	},
+}
+
+var serverListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved servers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := inventory.Path()
+		if err != nil {
+			return err
+		}
+		inv, err := inventory.Load(path)
+		if err != nil {
+			return err
+		}
+		if len(inv.Servers) == 0 {
+			ui.Info("No servers saved yet, add one with 'bunkr server add <name> <user@host[:port]>'")
+			return nil
+		}
+		fmt.Printf("  %-2s %-20s %-36s %s\n", "", "NAME", "TARGET", "APPS")
+		for _, name := range inv.Names() {
+			srv := inv.Servers[name]
+			mark := ""
+			if name == inv.Default {
+				mark = "*"
+			}
+			apps := strings.Join(srv.Apps, ",")
+			if apps == "" {
+				apps = "-"
+			}
+			fmt.Printf("  %-2s %-20s %-36s %s\n", mark, name, srv.Target(), apps)
+		}
+		return nil
+	},
+}
+
+var serverRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Forget a saved server (nothing on the server is changed)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return editInventory(func(inv *inventory.Inventory) error {
+			if err := inv.Remove(args[0]); err != nil {
+				return err
+			}
+			ui.Success(fmt.Sprintf("Removed %s", args[0]))
+			return nil
+		})
+	},
+}
+
+func editInventory(edit func(*inventory.Inventory) error) error {
+	path, err := inventory.Path()
+	if err != nil {
+		return err
+	}
+	inv, err := inventory.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := edit(inv); err != nil {
+		return err
+	}
+	return inventory.Save(path, inv)
+}
+
+// resolveServer replaces --on with the target of a saved server, or of the
+// default server when --on isn't given. Using the default is noted on stderr
+// so it's never a surprise, without mixing into --json output.
+func resolveServer() error {
+	target, name, err := lookupServer(onFlag)
+	if err != nil {
+		return err
+	}
+	if onFlag == "" && target != "" {
+		fmt.Fprintf(os.Stderr, "Using default server %s (%s), pass --on to choose another\n", name, target)
+	}
+	onFlag, serverName = target, name
+	return nil
+}
+
+// lookupServer resolves a server name or target against the inventory, as
+// Inventory.Resolve does.
+func lookupServer(on string) (target, name string, err error) {
+	path, err := inventory.Path()
+	if err != nil {
+		return "", "", err
+	}
+	inv, err := inventory.Load(path)
+	if err != nil {
+		return "", "", err
+	}
+	return inv.Resolve(on)
+}
+
+// rememberHardened points the inventory at the login hardening leaves
+// behind, adding an entry named after the host if the server has none.
+func rememberHardened(sshPort int) {
+	if onFlag == "" {
+		return
+	}
+	err := editInventory(func(inv *inventory.Inventory) error {
+		srv, err := inventory.ParseTarget(onFlag)
+		if err != nil {
+			return err
+		}
+		if serverName == "" {
+			serverName = srv.Host
+			inv.Servers[serverName] = &srv
+			ui.Info(fmt.Sprintf("Saved this server as %s, use --on %s from now on", serverName, serverName))
+		}
+		entry := inv.Servers[serverName]
+		entry.User, entry.Port = "bunkr", sshPort
+		return nil
+	})
+	if err != nil {
+		ui.Warn("Failed to update the server inventory: " + err.Error())
+	}
+}
+
+// rememberApps records the apps in s against the named inventory entry.
+func rememberApps(name string, s *state.State) {
+	if name == "" {
+		return
+	}
+	err := editInventory(func(inv *inventory.Inventory) error {
+		entry, ok := inv.Servers[name]
+		if !ok {
+			return nil
+		}
+		entry.Apps = entry.Apps[:0]
+		for name := range s.Recipes {
+			entry.Apps = append(entry.Apps, name)
+		}
+		slices.Sort(entry.Apps)
+		return nil
+	})
+	if err != nil {
+		ui.Warn("Failed to update the server inventory: " + err.Error())
+	}
+}
+
+func init() {
+	serverAddCmd.Flags().BoolVar(&serverDefaultFlag, "default", false, "use this server when --on isn't given")
+	serverCmd.AddCommand(serverAddCmd)
+	serverCmd.AddCommand(serverListCmd)
+	serverCmd.AddCommand(serverRemoveCmd)
+	rootCmd.AddCommand(serverCmd)
+}
@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
 
 	"github.com/pankajbeniwal/bunkr/internal/docker"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
 	"github.com/pankajbeniwal/bunkr/internal/state"
 	"github.com/pankajbeniwal/bunkr/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+var statusJSONFlag bool
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show status of installed apps",
@@ -25,11 +32,15 @@ var statusCmd = &cobra.Command{
 			return err
 		}
 
-		s, err := state.Load(ctx, exec)
+		s, err := loadState(ctx, exec)
 		if err != nil {
 			return err
 		}
 
+		if statusJSONFlag {
+			return printStatusJSON(ctx, exec, s)
+		}
+
 		if len(s.Recipes) == 0 {
 			ui.Info("No apps installed")
 			return nil
@@ -39,7 +50,9 @@ var statusCmd = &cobra.Command{
 		fmt.Printf("\n  %-20s %-10s %-30s %-12s %-10s %s\n", "NAME", "VERSION", "DOMAIN", "ACCESS", "PORT", "STATUS")
 		fmt.Printf("  %-20s %-10s %-30s %-12s %-10s %s\n", "----", "-------", "------", "------", "----", "------")
 
-		for name, r := range s.Recipes {
+		names := appNames(s)
+		for _, name := range names {
+			r := s.Recipes[name]
 			status := "unknown"
 			statuses, err := docker.ComposeStatus(ctx, exec, name)
 			if err == nil && len(statuses) > 0 {
@@ -50,6 +63,34 @@ var statusCmd = &cobra.Command{
 				access = "tailscale"
 			}
 			fmt.Printf("  %-20s %-10s %-30s %-12s %-10d %s\n", name, r.Version, r.Domain, access, r.Port, status)
+			if len(r.Endpoints) > 1 {
+				for _, ep := range r.Endpoints[1:] {
+					route := ep.Domain + ep.Path
+					if route == "" {
+						route = "-"
+					}
+					fmt.Printf("  %-20s %-10s %-30s %-12s %-10d\n", "  └ "+ep.Name, "", route, ep.Protocol, ep.Port)
+				}
+			}
+		}
+		fmt.Println()
+
+		ui.Header("Resources")
+		fmt.Printf("\n  %-20s %-24s %-22s %-24s %s\n", "APP", "SERVICE", "CPU", "MEMORY", "PIDS")
+		fmt.Printf("  %-20s %-24s %-22s %-24s %s\n", "---", "-------", "---", "------", "----")
+		for _, name := range names {
+			stats, err := docker.ComposeStats(ctx, exec, name)
+			if err != nil {
+				continue
+			}
+			for _, cs := range stats {
+				fmt.Printf("  %-20s %-24s %-22s %-24s %s\n",
+					name, cs.Service,
+					usageAgainst(cs.CPUPercent, formatCPUs(cs.NanoCPUs)),
+					usageAgainst(cs.MemoryUsage, formatBytes(cs.MemoryLimit)),
+					usageAgainst(cs.PIDs, formatCount(cs.PidsLimit)),
+				)
+			}
 		}
 		fmt.Println()
 
@@ -61,6 +102,94 @@ var statusCmd = &cobra.Command{
 	},
 }
 
+type appStatus struct {
+	Name string `json:"name"`
+	state.RecipeState
+	Status  string        `json:"status"`
+	History []state.Event `json:"history"`
+}
+
+func printStatusJSON(ctx context.Context, exec executor.Executor, s *state.State) error {
+	names := appNames(s)
+	apps := make([]appStatus, 0, len(names))
+	for _, name := range names {
+		app := appStatus{Name: name, RecipeState: s.Recipes[name], Status: "unknown", History: s.History(name)}
+		if statuses, err := docker.ComposeStatus(ctx, exec, name); err == nil && len(statuses) > 0 {
+			app.Status = statuses[0].Status
+		}
+		if app.History == nil {
+			app.History = []state.Event{}
+		}
+		apps = append(apps, app)
+	}
+
+	// Server-wide events, plus those of apps that are no longer installed
+	server := []state.Event{}
+	for _, e := range s.Events {
+		if _, ok := s.Recipes[e.App]; !ok {
+			server = append(server, e)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Hardening state.HardeningState `json:"hardening"`
+		Tailscale state.TailscaleState `json:"tailscale"`
+		Apps      []appStatus          `json:"apps"`
+		History   []state.Event        `json:"history"`
+	}{s.Hardening, s.Tailscale, apps, server})
+}
+
+// appNames returns the installed apps in name order, so every listing shows
+// them the same way.
+func appNames(s *state.State) []string {
+	names := make([]string, 0, len(s.Recipes))
+	for name := range s.Recipes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func usageAgainst(usage, limit string) string {
+	if usage == "" {
+		usage = "-"
+	}
+	return usage + " / " + limit
+}
+
+func formatCPUs(nano int64) string {
+	if nano <= 0 {
+		return "unlimited"
+	}
+	return strconv.FormatFloat(float64(nano)/1e9, 'f', -1, 64) + " cpus"
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n <= 0 {
+		return "unlimited"
+	}
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.4g%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatCount(n int64) string {
+	if n <= 0 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(n, 10)
+}
+
 func init() {
+	statusCmd.Flags().BoolVar(&statusJSONFlag, "json", false, "print state, container status and history as JSON")
 	rootCmd.AddCommand(statusCmd)
 }
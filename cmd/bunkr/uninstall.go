@@ -7,7 +7,6 @@ import (
 	"github.com/pankajbeniwal/bunkr/internal/caddy"
 	"github.com/pankajbeniwal/bunkr/internal/docker"
 	"github.com/pankajbeniwal/bunkr/internal/state"
-	"github.com/pankajbeniwal/bunkr/internal/tailscale"
 	"github.com/pankajbeniwal/bunkr/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -18,7 +17,7 @@ var uninstallCmd = &cobra.Command{
 	Use:   "uninstall <recipe>",
 	Short: "Remove an installed app",
 	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		if err := requireRemote(); err != nil {
 			return err
 		}
@@ -30,8 +29,13 @@ var uninstallCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		unlock, err := lockServer(ctx, exec)
+		if err != nil {
+			return err
+		}
+		defer unlock()
 
-		s, err := state.Load(ctx, exec)
+		s, err := loadState(ctx, exec)
 		if err != nil {
 			return err
 		}
@@ -40,6 +44,15 @@ var uninstallCmd = &cobra.Command{
 			return fmt.Errorf("recipe %s is not installed", name)
 		}
 
+		if err := takeSnapshot(ctx, exec, name); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				recordFailure(ctx, exec, name, err)
+			}
+		}()
+
 		ui.Header(fmt.Sprintf("Uninstalling %s...", name))
 
 		// Stop containers
@@ -50,19 +63,8 @@ var uninstallCmd = &cobra.Command{
 
 		// Remove network config
 		rs := s.Recipes[name]
-		if rs.Private {
-			if err := tailscale.RemoveServe(ctx, exec, rs.Port); err != nil {
-				ui.Warn("Failed to remove Tailscale serve: " + err.Error())
-			} else {
-				ui.Success("Tailscale serve removed")
-			}
-		} else {
-			if err := caddy.RemoveBlock(ctx, exec, name); err != nil {
-				ui.Warn("Failed to remove Caddy config: " + err.Error())
-			} else {
-				ui.Success("Caddy config removed")
-			}
-
+		unexposeEndpoints(ctx, exec, name, rs.Private, rs.EndpointList())
+		if !rs.Private {
 			if err := caddy.Reload(ctx, exec); err != nil {
 				ui.Warn("Caddy reload failed")
 			}
@@ -77,9 +79,15 @@ var uninstallCmd = &cobra.Command{
 
 		// Update state
 		delete(s.Recipes, name)
+		detail := "version " + rs.Version + ", data kept"
+		if purgeFlag {
+			detail = "version " + rs.Version + ", data removed"
+		}
+		s.Record(newEvent(name, state.EventUninstalled, detail))
 		if err := state.Save(ctx, exec, s); err != nil {
 			return err
 		}
+		rememberApps(serverName, s)
 
 		ui.Result(fmt.Sprintf("%s has been uninstalled", name))
 		return nil
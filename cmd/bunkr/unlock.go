@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/lock"
+	"github.com/pankajbeniwal/bunkr/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var forceUnlockFlag bool
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Show or clear the server lock left by an interrupted command",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireRemote(); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		exec, err := newExecutor()
+		if err != nil {
+			return err
+		}
+
+		holder, err := lock.Current(ctx, exec)
+		if err != nil {
+			return err
+		}
+		if holder == nil {
+			ui.Info("Server is not locked")
+			return nil
+		}
+
+		ui.Info(fmt.Sprintf("Locked by %s", holder))
+		if !forceUnlockFlag {
+			return fmt.Errorf("make sure that command is no longer running, then run 'bunkr unlock --force'")
+		}
+		if err := lock.ForceUnlock(ctx, exec); err != nil {
+			return err
+		}
+		ui.Success("Lock removed")
+		return nil
+	},
+}
+
+// lockServer takes the server lock for the running command, waiting up to
+// --lock-timeout for another bunkr to finish. Call the returned func when done.
+func lockServer(ctx context.Context, exec executor.Executor) (func(), error) {
+	user, host := operator()
+	holder := lock.Holder{Host: host, User: user, Command: commandLine()}
+
+	l, err := lock.Acquire(ctx, exec, holder, lockTimeoutFlag, func(h lock.Holder) {
+		ui.Info(fmt.Sprintf("Waiting for %s...", h))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w\n\nTry again once it finishes. If it was interrupted, run 'bunkr unlock --force'.", err)
+	}
+	return func() {
+		if err := l.Release(ctx); err != nil {
+			ui.Warn("Failed to release server lock: " + err.Error())
+		}
+	}, nil
+}
+
+func init() {
+	unlockCmd.Flags().BoolVar(&forceUnlockFlag, "force", false, "remove the lock even though another command may hold it")
+	rootCmd.AddCommand(unlockCmd)
+}
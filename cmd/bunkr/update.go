@@ -2,20 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
 	"github.com/pankajbeniwal/bunkr/internal/docker"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
 	"github.com/pankajbeniwal/bunkr/internal/recipe"
 	"github.com/pankajbeniwal/bunkr/internal/state"
 	"github.com/pankajbeniwal/bunkr/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+var toVersionFlag string
+
 var updateCmd = &cobra.Command{
 	Use:   "update <recipe>",
-	Short: "Update an installed app to the latest version",
+	Short: "Update an installed app to the latest (or a specific) version",
 	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		if err := requireRemote(); err != nil {
 			return err
 		}
@@ -27,8 +34,13 @@ var updateCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		unlock, err := lockServer(ctx, exec)
+		if err != nil {
+			return err
+		}
+		defer unlock()
 
-		s, err := state.Load(ctx, exec)
+		s, err := loadState(ctx, exec)
 		if err != nil {
 			return err
 		}
@@ -40,7 +52,7 @@ var updateCmd = &cobra.Command{
 
 		ui.Header(fmt.Sprintf("Checking for updates to %s...", name))
 
-		latest, err := recipe.Fetch(name)
+		latest, err := recipe.FetchVersion(name, toVersionFlag)
 		if err != nil {
 			return err
 		}
@@ -50,22 +62,137 @@ var updateCmd = &cobra.Command{
 			return nil
 		}
 
-		ui.Info(fmt.Sprintf("Updating %s: %s → %s", name, current.Version, latest.Version))
+		if recipe.CompareVersions(latest.Version, current.Version) < 0 {
+			ui.Info(fmt.Sprintf("Downgrading %s: %s → %s", name, current.Version, latest.Version))
+		} else {
+			ui.Info(fmt.Sprintf("Updating %s: %s → %s", name, current.Version, latest.Version))
+		}
+		if recipe.CrossesMajor(current.Version, latest.Version) {
+			ui.Warn("This crosses a major version — check the release notes for breaking changes and back up your data first")
+		}
 
-		// Pull new images
-		if err := docker.ComposePull(ctx, exec, name); err != nil {
-			return fmt.Errorf("failed to pull images: %w", err)
+		applyOverrides(latest, current.Limits, current.Restart)
+		if err := recipe.ApplySettings(latest, current.Settings); err != nil {
+			return err
 		}
-		ui.Success("Images pulled")
 
-		// Regenerate compose file with new image
-		composeData, err := recipe.GenerateCompose(latest, map[string]string{"DOMAIN": current.Domain}, current.Port)
+		// Carry over the prompt answers and secrets stored at install; only
+		// prompts new to this version are asked.
+		dir := fmt.Sprintf("/opt/bunkr/%s", name)
+		envPath := dir + "/.env"
+		previousEnv, err := exec.ReadFile(ctx, envPath)
 		if err != nil {
+			return fmt.Errorf("failed to read stored values: %w", err)
+		}
+		secretKeys := latest.SecretKeys()
+		stored := recipe.ParseEnv(previousEnv)
+		answers, missing := recipe.ReuseValues(latest, stored)
+		if len(missing) > 0 {
+			ui.Header(fmt.Sprintf("Configuring new settings for %s...", name))
+			asked, err := recipe.PromptUser(missing)
+			if err != nil {
+				return err
+			}
+			for k, v := range asked {
+				answers[k] = v
+			}
+		}
+		values := recipe.MergeValues(answers, latest.Environment)
+
+		if err := takeSnapshot(ctx, exec, name); err != nil {
 			return err
 		}
+		defer func() {
+			if err != nil {
+				recordFailure(ctx, exec, name, err)
+			}
+		}()
+		fromVersion := current.Version
 
-		dir := fmt.Sprintf("/opt/bunkr/%s", name)
-		if err := exec.WriteFile(ctx, dir+"/docker-compose.yml", composeData, 0644); err != nil {
+		// Pre-update hooks run against the current containers; any failure
+		// aborts before anything is changed.
+		pre, post := latest.UpdateHooks(current.Version, latest.Version)
+		hookEnv := map[string]string{"BUNKR_FROM_VERSION": current.Version, "BUNKR_TO_VERSION": latest.Version}
+		if len(pre) > 0 {
+			ui.Info("Running pre-update hooks...")
+			if err := runHooks(ctx, exec, name, pre, hookEnv); err != nil {
+				return fmt.Errorf("update aborted, %s is unchanged: %w", name, err)
+			}
+		}
+
+		// Regenerate compose and .env with new image. Endpoints new to this
+		// version get ports nothing else on the host is using
+		if err := scanPorts(ctx, exec, s); err != nil {
+			return err
+		}
+		previous := current.EndpointList()
+		endpoints := allocateEndpoints(s, latest, values, previous)
+		composeData, err := recipe.GenerateCompose(latest, values, hostPorts(endpoints)...)
+		if err != nil {
+			return err
+		}
+		// Rendered up front so a bad template fails before anything is written
+		files, err := recipe.RenderFiles(latest, values)
+		if err != nil {
+			return err
+		}
+
+		composePath := dir + "/docker-compose.yml"
+		previousCompose, err := exec.ReadFile(ctx, composePath)
+		if err != nil {
+			return fmt.Errorf("failed to read current compose file: %w", err)
+		}
+		// Until the containers restart, a failure puts back the files the
+		// running containers were started from
+		restore := func() {
+			if restoreErr := exec.WriteFile(ctx, composePath, previousCompose, 0644); restoreErr != nil {
+				ui.Warn("Failed to restore previous compose file: " + restoreErr.Error())
+			}
+			if restoreErr := exec.WriteFile(ctx, envPath, previousEnv, 0600); restoreErr != nil {
+				ui.Warn("Failed to restore previous .env file: " + restoreErr.Error())
+			}
+		}
+		if err := exec.WriteFile(ctx, composePath, composeData, 0644); err != nil {
+			restore()
+			return err
+		}
+		if err := exec.WriteFile(ctx, envPath, recipe.GenerateEnv(values), 0600); err != nil {
+			restore()
+			return err
+		}
+
+		// Pull new images while the old containers keep running
+		if err := docker.ComposePull(ctx, exec, name); err != nil {
+			restore()
+			return fmt.Errorf("update aborted, failed to pull images: %w", err)
+		}
+		ui.Success("Images pulled")
+
+		// Regenerate config files, showing what changed
+		var secrets []string
+		for _, k := range secretKeys {
+			secrets = append(secrets, stored[k], values[k])
+		}
+		for _, f := range files {
+			path := dir + "/" + f.Path
+			if diff := diffRemoteFile(ctx, exec, path, f.Content, secrets); diff != "" {
+				ui.Info(fmt.Sprintf("Changes to %s:", f.Path))
+				ui.Diff(diff)
+			}
+		}
+		if err := writeAppFiles(ctx, exec, dir, files); err != nil {
+			restore()
+			return fmt.Errorf("update aborted, %s is still running %s (config files may be partly updated, 'bunkr rollback' restores them): %w", name, current.Version, err)
+		}
+
+		// Save the new version and ports before the containers restart
+		current.Version = latest.Version
+		current.Endpoints = endpoints
+		current.Domain = endpoints[0].Domain
+		current.Port = endpoints[0].Port
+		current.ContainerPort = endpoints[0].ContainerPort
+		s.Recipes[name] = current
+		if err := state.Save(ctx, exec, s); err != nil {
 			return err
 		}
 
@@ -78,18 +205,95 @@ var updateCmd = &cobra.Command{
 		}
 		ui.Success("Containers restarted")
 
-		// Update state
-		current.Version = latest.Version
-		s.Recipes[name] = current
+		// Re-route traffic if the recipe added, removed or moved endpoints
+		if endpointsChanged(previous, endpoints) {
+			unexposeEndpoints(ctx, exec, name, current.Private, previous)
+			if err := exposeEndpoints(ctx, exec, name, current.Private, endpoints); err != nil {
+				return err
+			}
+			if !current.Private {
+				if err := caddy.Reload(ctx, exec); err != nil {
+					ui.Warn("Caddy reload failed — you may need to run 'caddy reload' manually")
+				}
+			}
+		}
+
+		s.Record(newEvent(name, state.EventUpdated, fmt.Sprintf("from %s to %s", fromVersion, latest.Version)))
+		var changed []string
+		for _, p := range missing {
+			changed = append(changed, p.Key)
+		}
+		if endpointsChanged(previous, endpoints) {
+			changed = append(changed, "endpoints")
+		}
+		if len(changed) > 0 {
+			s.Record(newEvent(name, state.EventReconfigured, strings.Join(changed, ", ")))
+		}
 		if err := state.Save(ctx, exec, s); err != nil {
 			return err
 		}
 
+		// The new version is running and routed by now, so a failing hook
+		// is recorded after the update rather than instead of it
+		if len(post) > 0 {
+			ui.Info("Running post-update hooks...")
+			if err := runHooks(ctx, exec, name, post, hookEnv); err != nil {
+				ui.Error(fmt.Sprintf("%s is running %s but a post-update hook failed", name, latest.Version))
+				return err
+			}
+		}
+
 		ui.Result(fmt.Sprintf("%s updated to %s", name, latest.Version))
 		return nil
 	},
 }
 
+func runHooks(ctx context.Context, exec executor.Executor, name string, hooks []recipe.Hook, env map[string]string) error {
+	for _, h := range hooks {
+		service := h.Service
+		if service == "" {
+			service = name
+		}
+		label := h.Name
+		if label == "" {
+			label = service
+		}
+		if err := docker.RunHook(ctx, exec, name, service, h.Run, env); err != nil {
+			ui.Error(fmt.Sprintf("Hook %s failed", label))
+			return fmt.Errorf("hook %s failed: %w", label, err)
+		}
+		ui.Success(fmt.Sprintf("Hook %s complete", label))
+	}
+	return nil
+}
+
+// diffRemoteFile returns a unified diff between the file at path and content,
+// computed on the server with secrets masked in both. A file that doesn't
+// exist yet is diffed against /dev/null. Returns "" when they match.
+func diffRemoteFile(ctx context.Context, exec executor.Executor, path string, content []byte, secrets []string) string {
+	old := "/dev/null"
+	data, err := exec.ReadFile(ctx, path)
+	switch {
+	case err == nil:
+		old = path + ".bunkr-old"
+		if err := exec.WriteFile(ctx, old, recipe.MaskSecrets(data, secrets), 0600); err != nil {
+			return ""
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return ""
+	}
+	tmp := path + ".bunkr-new"
+	if err := exec.WriteFile(ctx, tmp, recipe.MaskSecrets(content, secrets), 0600); err != nil {
+		return ""
+	}
+	// The trailing rm keeps the exit status zero when the files differ
+	cmd := fmt.Sprintf("diff -u --label %s --label %s %s %s; rm -f %s.bunkr-old %s",
+		path, path+" (new)", old, tmp, path, tmp)
+	out, _ := exec.Run(ctx, cmd)
+	return out
+}
+
 func init() {
+	updateCmd.Flags().StringVar(&toVersionFlag, "to", "", "recipe version to move to (upgrade or downgrade)")
 	rootCmd.AddCommand(updateCmd)
 }
@@ -2,7 +2,11 @@ package caddy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pankajbeniwal/bunkr/internal/executor"
@@ -24,7 +28,26 @@ func initCaddyfile(ctx context.Context, exec executor.Executor) error {
 	return nil
 }
 
+// Route proxies requests under Path (all requests when empty) to a local port.
+type Route struct {
+	Path      string
+	StripPath bool
+	Port      int
+}
+
+// Site is one domain served by Caddy with one or more routes.
+type Site struct {
+	Domain string
+	Routes []Route
+}
+
 func AddBlock(ctx context.Context, exec executor.Executor, name string, domain string, hostPort int) error {
+	return AddSites(ctx, exec, name, []Site{{Domain: domain, Routes: []Route{{Port: hostPort}}}})
+}
+
+// AddSites writes every site for an app into a single marked block, replacing
+// any existing block for the app.
+func AddSites(ctx context.Context, exec executor.Executor, name string, sites []Site) error {
 	if err := initCaddyfile(ctx, exec); err != nil {
 		return err
 	}
@@ -37,15 +60,50 @@ func AddBlock(ctx context.Context, exec executor.Executor, name string, domain s
 		existing = []byte{}
 	}
 
-	block := fmt.Sprintf(
-		"\n# bunkr:%s\n%s {\n    reverse_proxy localhost:%d\n}\n# /bunkr:%s\n",
-		name, domain, hostPort, name,
-	)
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n# bunkr:%s\n", name)
+	for _, site := range sites {
+		writeSite(&b, name, site)
+	}
+	fmt.Fprintf(&b, "# /bunkr:%s\n", name)
 
-	content := string(existing) + block
+	content := string(existing) + b.String()
 	return exec.WriteFile(ctx, CaddyfilePath, []byte(content), 0644)
 }
 
+func writeSite(b *strings.Builder, name string, site Site) {
+	fmt.Fprintf(b, "%s {\n", site.Domain)
+	if len(site.Routes) == 1 && site.Routes[0].Path == "" {
+		fmt.Fprintf(b, "    reverse_proxy localhost:%d\n}\n", site.Routes[0].Port)
+		return
+	}
+
+	// Longest paths first so more specific routes are listed before the
+	// catch-all; Caddy evaluates handle blocks as mutually exclusive.
+	routes := append([]Route(nil), site.Routes...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Path) > len(routes[j].Path)
+	})
+	// Paths match exactly or below, so /api doesn't also catch /apiary.
+	// An inline matcher takes a single path, so each route gets a named
+	// one, and stripping is done explicitly
+	for i, r := range routes {
+		path := strings.TrimSuffix(r.Path, "/")
+		if r.Path == "" {
+			b.WriteString("    handle {\n")
+		} else {
+			matcher := fmt.Sprintf("@%s_%d", name, i)
+			fmt.Fprintf(b, "    %s path %s %s/*\n", matcher, path, path)
+			fmt.Fprintf(b, "    handle %s {\n", matcher)
+		}
+		if r.Path != "" && r.StripPath {
+			fmt.Fprintf(b, "        uri strip_prefix %s\n", path)
+		}
+		fmt.Fprintf(b, "        reverse_proxy localhost:%d\n    }\n", r.Port)
+	}
+	b.WriteString("}\n")
+}
+
 func RemoveBlock(ctx context.Context, exec executor.Executor, name string) error {
 	data, err := exec.ReadFile(ctx, CaddyfilePath)
 	if err != nil {
@@ -75,6 +133,118 @@ func RemoveBlock(ctx context.Context, exec executor.Executor, name string) error
 	return exec.WriteFile(ctx, CaddyfilePath, []byte(strings.Join(result, "\n")), 0644)
 }
 
+// SiteOwners maps each site address in the Caddyfile to the bunkr app whose
+// block defines it, or "" for sites bunkr doesn't manage. A missing Caddyfile
+// has no sites.
+func SiteOwners(ctx context.Context, exec executor.Executor) (map[string]string, error) {
+	owners := make(map[string]string)
+	data, err := exec.ReadFile(ctx, CaddyfilePath)
+	if err != nil {
+		return owners, nil
+	}
+
+	owner := ""
+	depth := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(trimmed, "# /bunkr:"); ok && name == owner {
+			owner = ""
+			continue
+		}
+		if name, ok := strings.CutPrefix(trimmed, "# bunkr:"); ok {
+			owner = name
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		// Site addresses open a top-level block: "a.com, b.com {"
+		if depth == 0 && strings.HasSuffix(trimmed, "{") {
+			for _, addr := range strings.Split(strings.TrimSuffix(trimmed, "{"), ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					owners[addr] = owner
+				}
+			}
+		}
+		depth += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+	}
+	return owners, nil
+}
+
+// AppSites reads back the sites in each bunkr block of the Caddyfile, as
+// written by AddSites. A missing Caddyfile has no sites.
+func AppSites(ctx context.Context, exec executor.Executor) (map[string][]Site, error) {
+	apps := make(map[string][]Site)
+	data, err := exec.ReadFile(ctx, CaddyfilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return apps, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Caddyfile: %w", err)
+	}
+
+	owner := ""
+	var route *Route
+	matchers := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(trimmed, "# /bunkr:"); ok && name == owner {
+			owner = ""
+			continue
+		}
+		if name, ok := strings.CutPrefix(trimmed, "# bunkr:"); ok {
+			owner = name
+			continue
+		}
+		if owner == "" || trimmed == "" {
+			continue
+		}
+
+		sites := apps[owner]
+		switch {
+		case strings.HasPrefix(trimmed, "@"):
+			// "@n8n_0 path /api /api/*"
+			if fields := strings.Fields(trimmed); len(fields) > 2 && fields[1] == "path" {
+				matchers[fields[0]] = fields[2]
+			}
+		case strings.HasPrefix(trimmed, "handle"):
+			// "handle {", "handle @n8n_0 {", or "handle /api* {" and
+			// "handle_path /api* {" as written by earlier versions
+			fields := strings.Fields(strings.TrimSuffix(trimmed, "{"))
+			route = &Route{StripPath: fields[0] == "handle_path"}
+			if len(fields) > 1 {
+				if path, ok := matchers[fields[1]]; ok {
+					route.Path = path
+				} else {
+					route.Path = strings.TrimSuffix(fields[1], "*")
+				}
+			}
+		case strings.HasPrefix(trimmed, "uri strip_prefix "):
+			if route != nil {
+				route.StripPath = true
+			}
+		case strings.HasPrefix(trimmed, "reverse_proxy localhost:"):
+			port, _ := strconv.Atoi(strings.TrimPrefix(trimmed, "reverse_proxy localhost:"))
+			if len(sites) == 0 {
+				continue
+			}
+			r := Route{Port: port}
+			if route != nil {
+				r.Path, r.StripPath = route.Path, route.StripPath
+			}
+			site := &sites[len(sites)-1]
+			site.Routes = append(site.Routes, r)
+		case strings.HasSuffix(trimmed, "{"):
+			route = nil
+			clear(matchers)
+			apps[owner] = append(sites, Site{Domain: strings.TrimSpace(strings.TrimSuffix(trimmed, "{"))})
+		case trimmed == "}":
+			route = nil
+		}
+	}
+	return apps, nil
+}
+
 func Reload(ctx context.Context, exec executor.Executor) error {
 	_, err := exec.Run(ctx, "systemctl reload caddy")
 	return err
@@ -2,6 +2,7 @@ package caddy
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -74,3 +75,141 @@ func TestAddBlock_NoCaddyfile(t *testing.T) {
 		t.Fatal("expected domain in new Caddyfile")
 	}
 }
+
+func TestAddSites_MultipleRoutes(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.Files[CaddyfilePath] = []byte("# Managed by bunkr\n")
+
+	sites := []Site{
+		{Domain: "analytics.example.com", Routes: []Route{
+			{Port: 8000},
+			{Path: "/api", Port: 8001},
+			{Path: "/console/", StripPath: true, Port: 8002},
+		}},
+		{Domain: "tracker.example.com", Routes: []Route{{Port: 8003}}},
+	}
+	if err := AddSites(context.Background(), mock, "plausible", sites); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content := string(mock.Files[CaddyfilePath])
+	expected := `
+# bunkr:plausible
+analytics.example.com {
+    @plausible_0 path /console /console/*
+    handle @plausible_0 {
+        uri strip_prefix /console
+        reverse_proxy localhost:8002
+    }
+    @plausible_1 path /api /api/*
+    handle @plausible_1 {
+        reverse_proxy localhost:8001
+    }
+    handle {
+        reverse_proxy localhost:8000
+    }
+}
+tracker.example.com {
+    reverse_proxy localhost:8003
+}
+# /bunkr:plausible
+`
+	if !strings.Contains(content, expected) {
+		t.Fatalf("unexpected Caddyfile:\n%s", content)
+	}
+
+	if err := RemoveBlock(context.Background(), mock, "plausible"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(mock.Files[CaddyfilePath]), "example.com") {
+		t.Fatal("expected all sites to be removed with the block")
+	}
+}
+
+func TestSiteOwners(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.Files[CaddyfilePath] = []byte(`{
+    email admin@example.com
+}
+
+legacy.example.com, www.legacy.example.com {
+    root * /var/www
+}
+
+# bunkr:n8n
+n8n.example.com {
+    handle /api* {
+        reverse_proxy localhost:3000
+    }
+    handle {
+        reverse_proxy localhost:3001
+    }
+}
+# /bunkr:n8n
+`)
+
+	owners, err := SiteOwners(context.Background(), mock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"legacy.example.com":     "",
+		"www.legacy.example.com": "",
+		"n8n.example.com":        "n8n",
+	}
+	if len(owners) != len(want) {
+		t.Fatalf("unexpected sites: %v", owners)
+	}
+	for domain, owner := range want {
+		if got, ok := owners[domain]; !ok || got != owner {
+			t.Fatalf("site %s: got owner %q (found %v), want %q", domain, got, ok, owner)
+		}
+	}
+}
+
+func TestAppSites(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+	if err := AddSites(ctx, mock, "ghost", []Site{{Domain: "blog.example.com", Routes: []Route{{Port: 2368}}}}); err != nil {
+		t.Fatal(err)
+	}
+	n8n := []Site{
+		{Domain: "n8n.example.com", Routes: []Route{{Path: "/hooks", StripPath: true, Port: 5679}, {Port: 5678}}},
+		{Domain: "api.example.com", Routes: []Route{{Path: "/v1", Port: 5680}}},
+	}
+	if err := AddSites(ctx, mock, "n8n", n8n); err != nil {
+		t.Fatal(err)
+	}
+	mock.Files[CaddyfilePath] = append([]byte("legacy.example.com {\n    reverse_proxy localhost:9000\n}\n"), mock.Files[CaddyfilePath]...)
+	// Blocks written before paths were matched exactly
+	mock.Files[CaddyfilePath] = append(mock.Files[CaddyfilePath], []byte(`
+# bunkr:umami
+umami.example.com {
+    handle_path /admin* {
+        reverse_proxy localhost:3100
+    }
+    handle /api* {
+        reverse_proxy localhost:3101
+    }
+}
+# /bunkr:umami
+`)...)
+
+	apps, err := AppSites(ctx, mock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(apps) != 3 {
+		t.Fatalf("expected only bunkr blocks, got %v", apps)
+	}
+	if got := apps["ghost"]; len(got) != 1 || got[0].Domain != "blog.example.com" || len(got[0].Routes) != 1 || got[0].Routes[0] != (Route{Port: 2368}) {
+		t.Fatalf("unexpected ghost sites: %+v", got)
+	}
+	if !reflect.DeepEqual(apps["n8n"], n8n) {
+		t.Fatalf("expected n8n sites to round-trip:\ngot  %+v\nwant %+v", apps["n8n"], n8n)
+	}
+	umami := []Site{{Domain: "umami.example.com", Routes: []Route{{Path: "/admin", StripPath: true, Port: 3100}, {Path: "/api", Port: 3101}}}}
+	if !reflect.DeepEqual(apps["umami"], umami) {
+		t.Fatalf("unexpected umami sites:\ngot  %+v\nwant %+v", apps["umami"], umami)
+	}
+}
@@ -3,6 +3,8 @@ package docker
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pankajbeniwal/bunkr/internal/executor"
@@ -56,8 +58,21 @@ func RunInit(ctx context.Context, exec executor.Executor, recipe string, initCmd
 // bind-mounts it into the container, and executes it via docker compose run.
 // This avoids shell quoting issues from double-escaping through SSH+sudo.
 func RunPostInit(ctx context.Context, exec executor.Executor, recipe string, commands []string) error {
+	if err := runScript(ctx, exec, recipe, recipe, "post-init", commands, nil, false); err != nil {
+		return fmt.Errorf("post-init failed: %w", err)
+	}
+	return nil
+}
+
+// RunHook runs commands in a one-off container of service, like RunPostInit,
+// with the app directory mounted at /bunkr and env passed to the container.
+func RunHook(ctx context.Context, exec executor.Executor, recipe, service string, commands []string, env map[string]string) error {
+	return runScript(ctx, exec, recipe, service, "hook", commands, env, true)
+}
+
+func runScript(ctx context.Context, exec executor.Executor, recipe, service, name string, commands []string, env map[string]string, mountAppDir bool) error {
 	dir := fmt.Sprintf("%s/%s", basePath, recipe)
-	scriptPath := dir + "/post-init.sh"
+	scriptPath := fmt.Sprintf("%s/%s.sh", dir, name)
 
 	// Build script content
 	script := "#!/bin/sh\nset -e\n"
@@ -67,20 +82,37 @@ func RunPostInit(ctx context.Context, exec executor.Executor, recipe string, com
 
 	// Write script to host
 	if err := exec.WriteFile(ctx, scriptPath, []byte(script), 0755); err != nil {
-		return fmt.Errorf("failed to write post-init script: %w", err)
+		return fmt.Errorf("failed to write %s script: %w", name, err)
+	}
+
+	var flags []string
+	if mountAppDir {
+		flags = append(flags, fmt.Sprintf("-v %s:/bunkr", dir))
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		flags = append(flags, fmt.Sprintf("-e %s=%s", k, env[k]))
 	}
 
 	// Run script inside container via bind-mount (--entrypoint overrides
 	// the image entrypoint so "sh" is executed directly)
 	cmd := fmt.Sprintf(
-		"docker compose -f %s run --rm --no-deps --entrypoint sh -v %s:/tmp/bunkr-post-init.sh:ro %s /tmp/bunkr-post-init.sh 2>&1",
-		composePath(recipe), scriptPath, recipe,
+		"docker compose -f %s run --rm --no-deps --entrypoint sh -v %s:/tmp/bunkr-%s.sh:ro %s%s /tmp/bunkr-%s.sh 2>&1",
+		composePath(recipe), scriptPath, name, joinFlags(flags), service, name,
 	)
 	_, err := exec.Run(ctx, cmd)
-	if err != nil {
-		return fmt.Errorf("post-init failed: %w", err)
+	return err
+}
+
+func joinFlags(flags []string) string {
+	if len(flags) == 0 {
+		return ""
 	}
-	return nil
+	return strings.Join(flags, " ") + " "
 }
 
 func ComposeDown(ctx context.Context, exec executor.Executor, recipe string, purge bool) error {
@@ -120,6 +152,92 @@ func ComposeStatus(ctx context.Context, exec executor.Executor, recipe string) (
 	return statuses, nil
 }
 
+// ComposeImages returns the image each running service of the recipe's
+// compose project was started from, keyed by service.
+func ComposeImages(ctx context.Context, exec executor.Executor, recipe string) (map[string]string, error) {
+	cmd := fmt.Sprintf("docker compose -f %s ps --format '{{.Service}} {{.Image}}'", composePath(recipe))
+	out, err := exec.Run(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 2 {
+			images[parts[0]] = parts[1]
+		}
+	}
+	return images, nil
+}
+
+// ContainerStats pairs a container's live usage with the limits it was
+// started with. Limits are zero when unset.
+type ContainerStats struct {
+	Service     string
+	CPUPercent  string // e.g. "1.25%"
+	MemoryUsage string // e.g. "183.2MiB"
+	PIDs        string
+	MemoryLimit int64 // bytes
+	NanoCPUs    int64
+	PidsLimit   int64
+}
+
+// ComposeStats reports resource usage and configured limits for every
+// running container in the recipe's compose project.
+func ComposeStats(ctx context.Context, exec executor.Executor, recipe string) ([]ContainerStats, error) {
+	ids := fmt.Sprintf("$(docker compose -f %s ps -q)", composePath(recipe))
+	inspectCmd := fmt.Sprintf(
+		`ids=%s; [ -z "$ids" ] || docker inspect --format '{{index .Config.Labels "com.docker.compose.service"}}|{{.HostConfig.Memory}}|{{.HostConfig.NanoCpus}}|{{.HostConfig.PidsLimit}}|{{.Name}}' $ids`,
+		ids,
+	)
+	out, err := exec.Run(ctx, inspectCmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []ContainerStats
+	byName := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.Split(line, "|")
+		if len(parts) != 5 {
+			continue
+		}
+		cs := ContainerStats{Service: parts[0]}
+		cs.MemoryLimit, _ = strconv.ParseInt(parts[1], 10, 64)
+		cs.NanoCPUs, _ = strconv.ParseInt(parts[2], 10, 64)
+		cs.PidsLimit, _ = strconv.ParseInt(parts[3], 10, 64)
+		byName[strings.TrimPrefix(parts[4], "/")] = len(stats)
+		stats = append(stats, cs)
+	}
+	if len(stats) == 0 {
+		return nil, nil
+	}
+
+	statsCmd := fmt.Sprintf(
+		`ids=%s; [ -z "$ids" ] || docker stats --no-stream --format '{{.Name}}|{{.CPUPerc}}|{{.MemUsage}}|{{.PIDs}}' $ids`,
+		ids,
+	)
+	out, err = exec.Run(ctx, statsCmd)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.Split(line, "|")
+		if len(parts) != 4 {
+			continue
+		}
+		i, ok := byName[parts[0]]
+		if !ok {
+			continue
+		}
+		stats[i].CPUPercent = parts[1]
+		stats[i].MemoryUsage = strings.TrimSpace(strings.Split(parts[2], "/")[0])
+		stats[i].PIDs = parts[3]
+	}
+	return stats, nil
+}
+
 func HealthCheck(ctx context.Context, exec executor.Executor, url string, timeout, interval int) error {
 	cmd := fmt.Sprintf(
 		"for i in $(seq 1 %d); do curl -sf %s > /dev/null 2>&1 && exit 0; sleep %d; done; exit 1",
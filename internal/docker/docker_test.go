@@ -67,3 +67,63 @@ func TestComposeDown_Purge(t *testing.T) {
 		t.Fatalf("unexpected command: %s", cmd)
 	}
 }
+
+func TestComposeStats(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	ids := "$(docker compose -f /opt/bunkr/n8n/docker-compose.yml ps -q)"
+	inspectCmd := `ids=` + ids + `; [ -z "$ids" ] || docker inspect --format '{{index .Config.Labels "com.docker.compose.service"}}|{{.HostConfig.Memory}}|{{.HostConfig.NanoCpus}}|{{.HostConfig.PidsLimit}}|{{.Name}}' $ids`
+	statsCmd := `ids=` + ids + `; [ -z "$ids" ] || docker stats --no-stream --format '{{.Name}}|{{.CPUPerc}}|{{.MemUsage}}|{{.PIDs}}' $ids`
+	mock.RunOutputs[inspectCmd] = "n8n|536870912|500000000|0|/n8n-n8n-1\nn8n_db|0|0|<nil>|/n8n-n8n_db-1\n"
+	mock.RunOutputs[statsCmd] = "n8n-n8n-1|1.25%|183.2MiB / 512MiB|12\nn8n-n8n_db-1|0.10%|40MiB / 1.9GiB|7\n"
+
+	stats, err := ComposeStats(context.Background(), mock, "n8n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 containers, got %d", len(stats))
+	}
+	app := stats[0]
+	if app.Service != "n8n" || app.MemoryLimit != 536870912 || app.NanoCPUs != 500000000 {
+		t.Fatalf("unexpected limits: %+v", app)
+	}
+	if app.CPUPercent != "1.25%" || app.MemoryUsage != "183.2MiB" || app.PIDs != "12" {
+		t.Fatalf("unexpected usage: %+v", app)
+	}
+	if stats[1].MemoryLimit != 0 || stats[1].PidsLimit != 0 {
+		t.Fatalf("expected unlimited db, got %+v", stats[1])
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	mock := executor.NewMockExecutor()
+
+	err := RunHook(context.Background(), mock, "n8n", "n8n_db", []string{"pg_dump -U n8n n8n > /bunkr/backup.sql"},
+		map[string]string{"BUNKR_TO_VERSION": "2.13.0", "BUNKR_FROM_VERSION": "2.12.2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	script := string(mock.Files["/opt/bunkr/n8n/hook.sh"])
+	if script != "#!/bin/sh\nset -e\npg_dump -U n8n n8n > /bunkr/backup.sql\n" {
+		t.Fatalf("unexpected script: %q", script)
+	}
+	cmd := mock.Calls[1].Args[0].(string)
+	expected := "docker compose -f /opt/bunkr/n8n/docker-compose.yml run --rm --no-deps --entrypoint sh -v /opt/bunkr/n8n/hook.sh:/tmp/bunkr-hook.sh:ro -v /opt/bunkr/n8n:/bunkr -e BUNKR_FROM_VERSION=2.12.2 -e BUNKR_TO_VERSION=2.13.0 n8n_db /tmp/bunkr-hook.sh 2>&1"
+	if cmd != expected {
+		t.Fatalf("unexpected command:\n%s\nwant:\n%s", cmd, expected)
+	}
+}
+
+func TestRunPostInit(t *testing.T) {
+	mock := executor.NewMockExecutor()
+
+	if err := RunPostInit(context.Background(), mock, "openclaw", []string{"echo hi"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd := mock.Calls[1].Args[0].(string)
+	expected := "docker compose -f /opt/bunkr/openclaw/docker-compose.yml run --rm --no-deps --entrypoint sh -v /opt/bunkr/openclaw/post-init.sh:/tmp/bunkr-post-init.sh:ro openclaw /tmp/bunkr-post-init.sh 2>&1"
+	if cmd != expected {
+		t.Fatalf("unexpected command:\n%s\nwant:\n%s", cmd, expected)
+	}
+}
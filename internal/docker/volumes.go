@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+)
+
+// volumeImage runs tar inside volumes, which aren't reachable from the host
+// by a stable path.
+const volumeImage = "alpine:3"
+
+// Volume is a named volume of a recipe's compose project. Key is its name in
+// the compose file; Name is the Docker volume, usually "<recipe>_<key>".
+type Volume struct {
+	Name string
+	Key  string
+}
+
+// ComposeVolumes lists the named volumes Docker Compose created for the
+// recipe, whether or not its containers are running.
+func ComposeVolumes(ctx context.Context, exec executor.Executor, recipe string) ([]Volume, error) {
+	cmd := fmt.Sprintf(`docker volume ls --filter label=com.docker.compose.project=%s --format '{{.Name}} {{.Label "com.docker.compose.volume"}}'`, recipe)
+	out, err := exec.Run(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes of %s: %w", recipe, err)
+	}
+
+	var volumes []Volume
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 2 {
+			volumes = append(volumes, Volume{Name: parts[0], Key: parts[1]})
+		}
+	}
+	return volumes, nil
+}
+
+// ExportVolume writes a tar of the volume's contents to w. The app should be
+// stopped so the copy is consistent.
+func ExportVolume(ctx context.Context, exec executor.Executor, v Volume, w io.Writer) error {
+	cmd := fmt.Sprintf("docker run --rm -v %s:/data:ro %s tar -C /data --numeric-owner -cf - .", v.Name, volumeImage)
+	if err := exec.Stream(ctx, cmd, nil, w); err != nil {
+		return fmt.Errorf("failed to export volume %s: %w", v.Name, err)
+	}
+	return nil
+}
+
+// ImportVolume creates the volume with the labels Compose expects, so the
+// recipe adopts it on 'up', and unpacks a tar from ExportVolume into it.
+func ImportVolume(ctx context.Context, exec executor.Executor, recipe string, v Volume, r io.Reader) error {
+	cmd := fmt.Sprintf("docker volume create --label com.docker.compose.project=%s --label com.docker.compose.volume=%s %s >/dev/null && "+
+		"docker run --rm -i -v %s:/data %s tar -C /data --numeric-owner -xf -",
+		recipe, v.Key, v.Name, v.Name, volumeImage)
+	if err := exec.Stream(ctx, cmd, r, nil); err != nil {
+		return fmt.Errorf("failed to import volume %s: %w", v.Name, err)
+	}
+	return nil
+}
+
+// ExportAppDir writes a tar of /opt/bunkr/<recipe> (compose file, .env and
+// config files) to w.
+func ExportAppDir(ctx context.Context, exec executor.Executor, recipe string, w io.Writer) error {
+	cmd := fmt.Sprintf("tar -C %s --numeric-owner -cf - %s", basePath, recipe)
+	if err := exec.Stream(ctx, cmd, nil, w); err != nil {
+		return fmt.Errorf("failed to export %s/%s: %w", basePath, recipe, err)
+	}
+	return nil
+}
+
+// ImportAppDir unpacks a tar from ExportAppDir under /opt/bunkr.
+func ImportAppDir(ctx context.Context, exec executor.Executor, recipe string, r io.Reader) error {
+	cmd := fmt.Sprintf("mkdir -p %s && tar -C %s --numeric-owner -xf -", basePath, basePath)
+	if err := exec.Stream(ctx, cmd, r, nil); err != nil {
+		return fmt.Errorf("failed to import %s/%s: %w", basePath, recipe, err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+)
+
+func TestComposeVolumes(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunOutputs[`docker volume ls --filter label=com.docker.compose.project=ghost --format '{{.Name}} {{.Label "com.docker.compose.volume"}}'`] = "ghost_ghost_content ghost_content\nghost_db_data db_data\n"
+
+	volumes, err := ComposeVolumes(context.Background(), mock, "ghost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Volume{{Name: "ghost_ghost_content", Key: "ghost_content"}, {Name: "ghost_db_data", Key: "db_data"}}
+	if len(volumes) != len(want) || volumes[0] != want[0] || volumes[1] != want[1] {
+		t.Fatalf("got %+v, want %+v", volumes, want)
+	}
+}
+
+func TestExportImportVolume(t *testing.T) {
+	src := executor.NewMockExecutor()
+	dst := executor.NewMockExecutor()
+	ctx := context.Background()
+	v := Volume{Name: "ghost_db_data", Key: "db_data"}
+
+	exportCmd := "docker run --rm -v ghost_db_data:/data:ro alpine:3 tar -C /data --numeric-owner -cf - ."
+	src.RunOutputs[exportCmd] = "tar bytes"
+
+	var buf bytes.Buffer
+	if err := ExportVolume(ctx, src, v, &buf); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+	if err := ImportVolume(ctx, dst, "ghost", v, &buf); err != nil {
+		t.Fatalf("unexpected import error: %v", err)
+	}
+
+	if len(dst.Calls) != 1 {
+		t.Fatalf("expected one import command, got %v", dst.Calls)
+	}
+	importCmd := dst.Calls[0].Args[0].(string)
+	for _, want := range []string{
+		"docker volume create --label com.docker.compose.project=ghost --label com.docker.compose.volume=db_data ghost_db_data",
+		"docker run --rm -i -v ghost_db_data:/data alpine:3 tar -C /data --numeric-owner -xf -",
+	} {
+		if !strings.Contains(importCmd, want) {
+			t.Errorf("expected import command to contain %q, got %s", want, importCmd)
+		}
+	}
+	if string(dst.Stdin[importCmd]) != "tar bytes" {
+		t.Fatalf("expected the exported tar on stdin, got %q", dst.Stdin[importCmd])
+	}
+}
@@ -0,0 +1,417 @@
+// Package doctor compares what state.json says is on a server with what is
+// actually running there, and suggests how to fix any drift.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/docker"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/hardening"
+	"github.com/pankajbeniwal/bunkr/internal/preflight"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/tailscale"
+	"gopkg.in/yaml.v3"
+)
+
+// Result is one row of the report. Fix is a command or step that should
+// clear a warning or failure.
+type Result struct {
+	Subject string           `json:"subject"`
+	Check   string           `json:"check"`
+	Status  preflight.Status `json:"status"`
+	Detail  string           `json:"detail,omitempty"`
+	Fix     string           `json:"fix,omitempty"`
+}
+
+// Thresholds for resource pressure and certificate expiry.
+const (
+	memoryWarnPercent = 20
+	memoryFailPercent = 10
+	diskWarnPercent   = 15
+	diskFailPercent   = 5
+	certWarnDays      = 14
+)
+
+const (
+	memoryCmd = "awk '/^MemTotal:/ {t=$2} /^MemAvailable:/ {a=$2} END {print t, a}' /proc/meminfo"
+	diskCmd   = "df -Pk $(test -d /var/lib/docker && echo /var/lib/docker || echo /) | awk 'NR==2 {print $2, $4}'"
+)
+
+// now is swapped in tests so certificate expiry is stable.
+var now = time.Now
+
+// Run checks the server against s and returns the report. It only reads
+// from the server.
+func Run(ctx context.Context, exec executor.Executor, s *state.State) ([]Result, error) {
+	var results []Result
+	results = append(results, checkHardening(ctx, exec, s)...)
+	results = append(results, checkResources(ctx, exec)...)
+
+	sites, err := caddy.AppSites(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+	served, err := tailscale.Served(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := hardening.AllowedPorts(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(s.Recipes))
+	private := false
+	for name, app := range s.Recipes {
+		names = append(names, name)
+		private = private || app.Private
+	}
+	sort.Strings(names)
+
+	if private {
+		if ok, _ := tailscale.IsConnected(ctx, exec); ok {
+			results = append(results, Result{Subject: "server", Check: "tailscale", Status: preflight.Pass, Detail: "connected"})
+		} else {
+			results = append(results, Result{Subject: "server", Check: "tailscale", Status: preflight.Fail, Detail: "not connected, private apps are unreachable", Fix: "tailscale up"})
+		}
+	}
+
+	for _, name := range names {
+		app := s.Recipes[name]
+		results = append(results, checkContainers(ctx, exec, name, app)...)
+		if app.Private {
+			results = append(results, checkServe(name, app, served)...)
+			continue
+		}
+		results = append(results, checkRoutes(name, app, sites[name], allowed)...)
+		for _, domain := range appDomains(app) {
+			results = append(results, checkCert(ctx, exec, name, domain))
+		}
+	}
+	return results, nil
+}
+
+// Worst returns the most severe status in results.
+func Worst(results []Result) preflight.Status {
+	worst := preflight.Pass
+	for _, r := range results {
+		if r.Status > worst {
+			worst = r.Status
+		}
+	}
+	return worst
+}
+
+func checkHardening(ctx context.Context, exec executor.Executor, s *state.State) []Result {
+	fix := "bunkr init"
+	if s.Hardening.SSHPort != 0 {
+		fix = fmt.Sprintf("bunkr init --ssh-port %d", s.Hardening.SSHPort)
+	}
+
+	var results []Result
+	for _, step := range hardening.Steps(s.Hardening.SSHPort) {
+		r := Result{Subject: "server", Check: step.Name}
+		ok, err := step.Check(ctx, exec)
+		switch {
+		case err == nil && ok:
+			r.Status, r.Detail = preflight.Pass, step.Label
+		case s.Hardening.Steps[step.Name]:
+			r.Status, r.Detail, r.Fix = preflight.Fail, "recorded as applied but no longer in place", fix
+		default:
+			r.Status, r.Detail, r.Fix = preflight.Warn, "not applied", fix
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func checkResources(ctx context.Context, exec executor.Executor) []Result {
+	var results []Result
+
+	mem := Result{Subject: "server", Check: "memory"}
+	if total, avail, err := readPair(ctx, exec, memoryCmd); err != nil {
+		mem.Status, mem.Detail = preflight.Warn, err.Error()
+	} else {
+		mem.Status, mem.Detail = pressure(avail, total, memoryWarnPercent, memoryFailPercent)
+		if mem.Status != preflight.Pass {
+			mem.Fix = "bunkr status (per-app usage), then lower limits or add RAM"
+		}
+	}
+	results = append(results, mem)
+
+	disk := Result{Subject: "server", Check: "disk"}
+	if total, avail, err := readPair(ctx, exec, diskCmd); err != nil {
+		disk.Status, disk.Detail = preflight.Warn, err.Error()
+	} else {
+		disk.Status, disk.Detail = pressure(avail, total, diskWarnPercent, diskFailPercent)
+		if disk.Status != preflight.Pass {
+			disk.Fix = "docker system prune (removes unused images and build cache)"
+		}
+	}
+	results = append(results, disk)
+	return results
+}
+
+// readPair runs cmd and parses its two whitespace-separated numbers. The
+// meminfo and df commands both report kilobytes.
+func readPair(ctx context.Context, exec executor.Executor, cmd string) (int64, int64, error) {
+	out, err := exec.Run(ctx, cmd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read: %w", err)
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected output %q", strings.TrimSpace(out))
+	}
+	a, err1 := strconv.ParseInt(fields[0], 10, 64)
+	b, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("unexpected output %q", strings.TrimSpace(out))
+	}
+	return a, b, nil
+}
+
+func pressure(avail, total int64, warnPercent, failPercent int64) (preflight.Status, string) {
+	if total <= 0 {
+		return preflight.Warn, "unknown"
+	}
+	percent := avail * 100 / total
+	detail := fmt.Sprintf("%d%% free (%s of %s)", percent, formatKB(avail), formatKB(total))
+	switch {
+	case percent < failPercent:
+		return preflight.Fail, detail
+	case percent < warnPercent:
+		return preflight.Warn, detail
+	}
+	return preflight.Pass, detail
+}
+
+func formatKB(kb int64) string {
+	const mb = 1024
+	const gb = 1024 * mb
+	if kb >= gb {
+		return fmt.Sprintf("%.1fG", float64(kb)/gb)
+	}
+	return fmt.Sprintf("%dM", kb/mb)
+}
+
+func checkContainers(ctx context.Context, exec executor.Executor, name string, app state.RecipeState) []Result {
+	composeFile := fmt.Sprintf("/opt/bunkr/%s/docker-compose.yml", name)
+	upFix := fmt.Sprintf("docker compose -f %s up -d", composeFile)
+
+	data, err := exec.ReadFile(ctx, composeFile)
+	if err != nil {
+		return []Result{{Subject: name, Check: "compose file", Status: preflight.Fail, Detail: "missing", Fix: "bunkr rollback --list (restore from a snapshot)"}}
+	}
+	var cf struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		return []Result{{Subject: name, Check: "compose file", Status: preflight.Fail, Detail: err.Error(), Fix: "bunkr rollback --list (restore from a snapshot)"}}
+	}
+
+	var results []Result
+	if primary, ok := cf.Services[name]; ok {
+		if v := recipe.ImageVersion(primary.Image); v != app.Version {
+			results = append(results, Result{Subject: name, Check: "version", Status: preflight.Warn,
+				Detail: fmt.Sprintf("compose file runs %s, state says %s", v, app.Version), Fix: "bunkr adopt"})
+		}
+	}
+
+	statuses, err := docker.ComposeStatus(ctx, exec, name)
+	if err != nil {
+		return append(results, Result{Subject: name, Check: "containers", Status: preflight.Fail, Detail: err.Error(), Fix: upFix})
+	}
+	var down []string
+	for _, st := range statuses {
+		if st.Status != "running" {
+			down = append(down, fmt.Sprintf("%s %s", st.Name, st.Status))
+		}
+	}
+	switch {
+	case len(statuses) == 0:
+		results = append(results, Result{Subject: name, Check: "containers", Status: preflight.Fail, Detail: "no containers", Fix: upFix})
+	case len(down) > 0:
+		results = append(results, Result{Subject: name, Check: "containers", Status: preflight.Fail, Detail: strings.Join(down, ", "), Fix: upFix})
+	default:
+		results = append(results, Result{Subject: name, Check: "containers", Status: preflight.Pass, Detail: fmt.Sprintf("%d running", len(statuses))})
+	}
+
+	running, err := docker.ComposeImages(ctx, exec, name)
+	if err != nil {
+		return results
+	}
+	services := make([]string, 0, len(cf.Services))
+	for svc := range cf.Services {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+	var stale []string
+	for _, svc := range services {
+		if got, ok := running[svc]; ok && got != cf.Services[svc].Image {
+			stale = append(stale, fmt.Sprintf("%s runs %s, expected %s", svc, got, cf.Services[svc].Image))
+		}
+	}
+	if len(stale) > 0 {
+		results = append(results, Result{Subject: name, Check: "images", Status: preflight.Fail, Detail: strings.Join(stale, "; "), Fix: upFix})
+	} else if len(running) > 0 {
+		results = append(results, Result{Subject: name, Check: "images", Status: preflight.Pass, Detail: "match the compose file"})
+	}
+	return results
+}
+
+func checkRoutes(name string, app state.RecipeState, sites []caddy.Site, allowed map[string]bool) []Result {
+	restoreFix := "bunkr rollback --list (restore the Caddyfile from a snapshot)"
+
+	var results []Result
+	for _, ep := range app.EndpointList() {
+		check := "route " + ep.Name
+		if ep.Protocol == recipe.ProtocolTCP {
+			rule := fmt.Sprintf("%d/tcp", ep.Port)
+			if allowed[rule] {
+				results = append(results, Result{Subject: name, Check: check, Status: preflight.Pass, Detail: "ufw allows " + rule})
+			} else {
+				results = append(results, Result{Subject: name, Check: check, Status: preflight.Fail, Detail: "ufw doesn't allow " + rule, Fix: "ufw allow " + rule})
+			}
+			continue
+		}
+		if ep.Domain == "" {
+			continue
+		}
+		if len(sites) == 0 {
+			results = append(results, Result{Subject: name, Check: check, Status: preflight.Fail, Detail: "no Caddy block", Fix: restoreFix})
+			continue
+		}
+
+		port, found := 0, false
+		for _, site := range sites {
+			if site.Domain != ep.Domain {
+				continue
+			}
+			for _, rt := range site.Routes {
+				if strings.TrimSuffix(rt.Path, "/") == strings.TrimSuffix(ep.Path, "/") {
+					port, found = rt.Port, true
+				}
+			}
+		}
+		target := ep.Domain + ep.Path
+		switch {
+		case !found:
+			results = append(results, Result{Subject: name, Check: check, Status: preflight.Fail, Detail: "Caddy doesn't serve " + target, Fix: restoreFix})
+		case port != ep.Port:
+			results = append(results, Result{Subject: name, Check: check, Status: preflight.Fail,
+				Detail: fmt.Sprintf("%s proxies to port %d, state has %d", target, port, ep.Port), Fix: "bunkr adopt (if the Caddyfile is right) or " + restoreFix})
+		default:
+			results = append(results, Result{Subject: name, Check: check, Status: preflight.Pass, Detail: fmt.Sprintf("%s → %d", target, port)})
+		}
+	}
+	return results
+}
+
+func checkServe(name string, app state.RecipeState, served *tailscale.ServeConfig) []Result {
+	var results []Result
+	for _, ep := range app.EndpointList() {
+		check := "serve " + ep.Name
+		if ep.Protocol == recipe.ProtocolTCP {
+			if served.TCP[ep.Port] {
+				results = append(results, Result{Subject: name, Check: check, Status: preflight.Pass, Detail: fmt.Sprintf("tcp %d forwarded", ep.Port)})
+			} else {
+				results = append(results, Result{Subject: name, Check: check, Status: preflight.Fail, Detail: fmt.Sprintf("tcp %d not forwarded", ep.Port),
+					Fix: fmt.Sprintf("tailscale serve --bg --tcp=%d tcp://localhost:%d", ep.Port, ep.Port)})
+			}
+			continue
+		}
+
+		fix := fmt.Sprintf("tailscale serve --bg --https=443 http://localhost:%d", ep.Port)
+		if ep.Path != "" {
+			fix = fmt.Sprintf("tailscale serve --bg --https=443 --set-path %s http://localhost:%d", ep.Path, ep.Port)
+		}
+		path, ok := served.HTTP[ep.Port]
+		switch {
+		case !ok:
+			results = append(results, Result{Subject: name, Check: check, Status: preflight.Fail, Detail: fmt.Sprintf("port %d not served", ep.Port), Fix: fix})
+		case strings.TrimSuffix(path, "/") != strings.TrimSuffix(ep.Path, "/"):
+			results = append(results, Result{Subject: name, Check: check, Status: preflight.Warn, Detail: fmt.Sprintf("port %d served at %q, state has %q", ep.Port, path, ep.Path), Fix: fix})
+		default:
+			results = append(results, Result{Subject: name, Check: check, Status: preflight.Pass, Detail: fmt.Sprintf("port %d served", ep.Port)})
+		}
+	}
+	return results
+}
+
+// appDomains returns the distinct domains of an app's http endpoints.
+func appDomains(app state.RecipeState) []string {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, ep := range app.EndpointList() {
+		if ep.Protocol != recipe.ProtocolTCP && ep.Domain != "" && !seen[ep.Domain] {
+			seen[ep.Domain] = true
+			domains = append(domains, ep.Domain)
+		}
+	}
+	return domains
+}
+
+// certCmd asks the local Caddy for domain's certificate and prints the
+// verification result and expiry.
+func certCmd(domain string) string {
+	return fmt.Sprintf(`out=$(timeout 10 openssl s_client -connect 127.0.0.1:443 -servername %s -verify_hostname %s </dev/null 2>/dev/null); `+
+		`echo "$out" | grep -m1 'Verify return code'; echo "$out" | openssl x509 -noout -enddate 2>/dev/null`, domain, domain)
+}
+
+func checkCert(ctx context.Context, exec executor.Executor, name, domain string) Result {
+	r := Result{Subject: name, Check: "certificate " + domain}
+	fix := fmt.Sprintf("check DNS for %s points at this server, then: journalctl -u caddy | grep %s", domain, domain)
+
+	out, err := exec.Run(ctx, certCmd(domain))
+	if err != nil {
+		r.Status, r.Detail, r.Fix = preflight.Warn, "could not check: "+err.Error(), fix
+		return r
+	}
+
+	var verify, notAfter string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if v, ok := strings.CutPrefix(line, "Verify return code: "); ok {
+			verify = v
+		}
+		if v, ok := strings.CutPrefix(line, "notAfter="); ok {
+			notAfter = v
+		}
+	}
+	if verify == "" {
+		verify = "not verified"
+	}
+	if notAfter == "" {
+		r.Status, r.Detail, r.Fix = preflight.Fail, "no certificate served", fix
+		return r
+	}
+	expiry, err := time.Parse("Jan _2 15:04:05 2006 MST", notAfter)
+	if err != nil {
+		r.Status, r.Detail = preflight.Warn, "unreadable expiry "+notAfter
+		return r
+	}
+
+	days := int(expiry.Sub(now()).Hours() / 24)
+	switch {
+	case !strings.HasPrefix(verify, "0 "):
+		r.Status, r.Detail, r.Fix = preflight.Fail, "invalid: "+verify, fix
+	case days < 0:
+		r.Status, r.Detail, r.Fix = preflight.Fail, "expired "+expiry.Format("2006-01-02"), fix
+	case days < certWarnDays:
+		r.Status, r.Detail, r.Fix = preflight.Warn, fmt.Sprintf("expires in %d days, Caddy should have renewed it", days), fix
+	default:
+		r.Status, r.Detail = preflight.Pass, fmt.Sprintf("valid until %s", expiry.Format("2006-01-02"))
+	}
+	return r
+}
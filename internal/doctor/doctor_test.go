@@ -0,0 +1,167 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/preflight"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+)
+
+const ghostCompose = `services:
+  ghost:
+    image: ghost:6.19.2
+  ghost_db:
+    image: mysql:8.0
+`
+
+// healthy returns a server where ghost (public) and tool (private) match
+// the state it also returns.
+func healthy(t *testing.T) (*executor.MockExecutor, *state.State) {
+	t.Helper()
+	now = func() time.Time { return time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+	s := state.New()
+	s.Hardening.SSHPort = 2222
+	for _, step := range []string{"user", "ssh_hardening", "firewall", "fail2ban", "upgrades", "sysctl", "swap"} {
+		s.Hardening.Steps[step] = true
+	}
+	s.Recipes["ghost"] = state.RecipeState{Version: "6.19.2", Endpoints: []state.EndpointState{
+		{Name: "web", Protocol: "http", Domain: "blog.example.com", Port: 2368, ContainerPort: 2368},
+		{Name: "smtp", Protocol: "tcp", Port: 2525, ContainerPort: 25},
+	}}
+	s.Recipes["tool"] = state.RecipeState{Version: "1.0", Private: true, Endpoints: []state.EndpointState{
+		{Name: "web", Protocol: "http", Domain: "box.ts.net", Port: 18789, ContainerPort: 18789},
+	}}
+
+	mock.RunOutputs[memoryCmd] = "4000000 2000000\n"
+	mock.RunOutputs[diskCmd] = "50000000 30000000\n"
+	mock.RunOutputs["ufw status 2>/dev/null || true"] = "2525/tcp ALLOW Anywhere\n"
+	mock.RunOutputs["tailscale status --json 2>/dev/null || true"] = `{"BackendState": "Running"}`
+	mock.RunOutputs["tailscale serve status --json 2>/dev/null || true"] = `{"Web": {"box.ts.net:443": {"Handlers": {"/": {"Proxy": "http://localhost:18789"}}}}}`
+	if err := caddy.AddSites(ctx, mock, "ghost", []caddy.Site{{Domain: "blog.example.com", Routes: []caddy.Route{{Port: 2368}}}}); err != nil {
+		t.Fatal(err)
+	}
+	mock.RunOutputs[certCmd("blog.example.com")] = "Verify return code: 0 (ok)\nnotAfter=Aug 30 12:00:00 2026 GMT\n"
+
+	mock.Files["/opt/bunkr/ghost/docker-compose.yml"] = []byte(ghostCompose)
+	mock.RunOutputs["docker compose -f /opt/bunkr/ghost/docker-compose.yml ps --format '{{.Name}} {{.State}}'"] = "ghost-ghost-1 running\nghost-ghost_db-1 running\n"
+	mock.RunOutputs["docker compose -f /opt/bunkr/ghost/docker-compose.yml ps --format '{{.Service}} {{.Image}}'"] = "ghost ghost:6.19.2\nghost_db mysql:8.0\n"
+	mock.Files["/opt/bunkr/tool/docker-compose.yml"] = []byte("services:\n  tool:\n    image: acme/tool:1.0\n")
+	mock.RunOutputs["docker compose -f /opt/bunkr/tool/docker-compose.yml ps --format '{{.Name}} {{.State}}'"] = "tool-tool-1 running\n"
+	return mock, s
+}
+
+func find(results []Result, subject, check string) Result {
+	for _, r := range results {
+		if r.Subject == subject && r.Check == check {
+			return r
+		}
+	}
+	return Result{Subject: subject, Check: check, Status: -1}
+}
+
+func TestRun_Healthy(t *testing.T) {
+	mock, s := healthy(t)
+
+	results, err := Run(context.Background(), mock, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range results {
+		if r.Status != preflight.Pass {
+			t.Errorf("expected %s %s to pass, got %s: %s", r.Subject, r.Check, r.Status, r.Detail)
+		}
+	}
+	for _, check := range [][2]string{
+		{"server", "ssh_hardening"}, {"server", "memory"}, {"server", "disk"}, {"server", "tailscale"},
+		{"ghost", "containers"}, {"ghost", "images"}, {"ghost", "route web"}, {"ghost", "route smtp"},
+		{"ghost", "certificate blog.example.com"}, {"tool", "containers"}, {"tool", "serve web"},
+	} {
+		if r := find(results, check[0], check[1]); r.Status != preflight.Pass {
+			t.Errorf("expected a passing %s %s row", check[0], check[1])
+		}
+	}
+}
+
+func TestRun_Drift(t *testing.T) {
+	mock, s := healthy(t)
+	mock.RunErrors["test -f /etc/ssh/sshd_config.d/99-bunkr.conf"] = fmt.Errorf("not found")
+	mock.RunOutputs[memoryCmd] = "4000000 300000\n"
+	mock.RunOutputs[diskCmd] = "50000000 5000000\n"
+	mock.RunOutputs["docker compose -f /opt/bunkr/ghost/docker-compose.yml ps --format '{{.Name}} {{.State}}'"] = "ghost-ghost-1 running\nghost-ghost_db-1 exited\n"
+	mock.RunOutputs["docker compose -f /opt/bunkr/ghost/docker-compose.yml ps --format '{{.Service}} {{.Image}}'"] = "ghost ghost:6.18.0\nghost_db mysql:8.0\n"
+	mock.Files[caddy.CaddyfilePath] = []byte("# bunkr:ghost\nblog.example.com {\n    reverse_proxy localhost:2400\n}\n# /bunkr:ghost\n")
+	mock.RunOutputs["ufw status 2>/dev/null || true"] = ""
+	mock.RunOutputs[certCmd("blog.example.com")] = "Verify return code: 0 (ok)\nnotAfter=Jun  5 12:00:00 2026 GMT\n"
+	mock.RunOutputs["tailscale serve status --json 2>/dev/null || true"] = "{}"
+
+	results, err := Run(context.Background(), mock, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		subject, check string
+		status         preflight.Status
+		detail, fix    string
+	}{
+		{"server", "ssh_hardening", preflight.Fail, "no longer in place", "bunkr init --ssh-port 2222"},
+		{"server", "memory", preflight.Fail, "7% free", "bunkr status"},
+		{"server", "disk", preflight.Warn, "10% free", "docker system prune"},
+		{"ghost", "containers", preflight.Fail, "ghost-ghost_db-1 exited", "docker compose -f /opt/bunkr/ghost/docker-compose.yml up -d"},
+		{"ghost", "images", preflight.Fail, "ghost runs ghost:6.18.0, expected ghost:6.19.2", "up -d"},
+		{"ghost", "route web", preflight.Fail, "proxies to port 2400, state has 2368", "bunkr adopt"},
+		{"ghost", "route smtp", preflight.Fail, "ufw doesn't allow 2525/tcp", "ufw allow 2525/tcp"},
+		{"ghost", "certificate blog.example.com", preflight.Warn, "expires in 4 days", "journalctl -u caddy"},
+		{"tool", "serve web", preflight.Fail, "port 18789 not served", "tailscale serve --bg --https=443 http://localhost:18789"},
+	} {
+		r := find(results, tt.subject, tt.check)
+		if r.Status != tt.status || !strings.Contains(r.Detail, tt.detail) || !strings.Contains(r.Fix, tt.fix) {
+			t.Errorf("%s %s: got %s %q (fix %q), want %s containing %q (fix %q)", tt.subject, tt.check, r.Status, r.Detail, r.Fix, tt.status, tt.detail, tt.fix)
+		}
+	}
+	if Worst(results) != preflight.Fail {
+		t.Fatal("expected the report to fail")
+	}
+}
+
+func TestRun_Certificate(t *testing.T) {
+	for name, tt := range map[string]struct {
+		out    string
+		status preflight.Status
+		detail string
+	}{
+		"expired":  {"Verify return code: 10 (certificate has expired)\nnotAfter=Jan  1 00:00:00 2026 GMT\n", preflight.Fail, "invalid: 10 (certificate has expired)"},
+		"mismatch": {"Verify return code: 62 (hostname mismatch)\nnotAfter=Dec  1 00:00:00 2026 GMT\n", preflight.Fail, "hostname mismatch"},
+		"missing":  {"", preflight.Fail, "no certificate served"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mock, _ := healthy(t)
+			mock.RunOutputs[certCmd("blog.example.com")] = tt.out
+			r := checkCert(context.Background(), mock, "ghost", "blog.example.com")
+			if r.Status != tt.status || !strings.Contains(r.Detail, tt.detail) {
+				t.Fatalf("got %s %q, want %s containing %q", r.Status, r.Detail, tt.status, tt.detail)
+			}
+		})
+	}
+}
+
+func TestResult_JSON(t *testing.T) {
+	data, err := json.Marshal(Result{Subject: "ghost", Check: "containers", Status: preflight.Fail, Fix: "docker compose up -d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"subject":"ghost","check":"containers","status":"fail","fix":"docker compose up -d"}`
+	if string(data) != want {
+		t.Fatalf("got %s, want %s", data, want)
+	}
+}
@@ -2,11 +2,34 @@ package executor
 
 import (
 	"context"
+	"errors"
+	"io"
 	"os"
 )
 
 type Executor interface {
 	Run(ctx context.Context, cmd string) (string, error)
 	WriteFile(ctx context.Context, path string, content []byte, mode os.FileMode) error
+	// ReadFile returns an error wrapping os.ErrNotExist when path is missing.
 	ReadFile(ctx context.Context, path string) ([]byte, error)
+	// Stream runs cmd with stdin and stdout attached, for data too large to
+	// hold in memory. Either may be nil.
+	Stream(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error
+}
+
+// Pipe feeds everything produce writes into consume, through this process.
+// It is used to copy data from one server to another.
+func Pipe(produce func(io.Writer) error, consume func(io.Reader) error) error {
+	pr, pw := io.Pipe()
+	produced := make(chan error, 1)
+	go func() {
+		err := produce(pw)
+		pw.CloseWithError(err)
+		produced <- err
+	}()
+
+	err := consume(pr)
+	// Unblocks produce if consume stopped reading early
+	pr.CloseWithError(errors.New("receiving side stopped reading"))
+	return errors.Join(<-produced, err)
 }
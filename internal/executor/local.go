@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 )
 
 type LocalExecutor struct{}
@@ -26,7 +28,26 @@ func (l *LocalExecutor) Run(ctx context.Context, cmd string) (string, error) {
 }
 
 func (l *LocalExecutor) WriteFile(_ context.Context, path string, content []byte, mode os.FileMode) error {
-	return os.WriteFile(path, content, mode)
+	// Match RemoteExecutor: create parent dirs and apply mode to existing files
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+func (l *LocalExecutor) Stream(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	var stderr bytes.Buffer
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, stderr.String())
+	}
+	return nil
 }
 
 func (l *LocalExecutor) ReadFile(_ context.Context, path string) ([]byte, error) {
@@ -2,8 +2,10 @@ package executor
 
 import (
 	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -49,3 +51,63 @@ func TestLocalExecutor_WriteReadFile(t *testing.T) {
 		t.Fatalf("expected mode 0644, got %v", info.Mode().Perm())
 	}
 }
+
+func TestLocalExecutor_WriteFile_CreatesParents(t *testing.T) {
+	exec := NewLocalExecutor()
+	path := filepath.Join(t.TempDir(), "config", "app.json")
+
+	if err := exec.WriteFile(context.Background(), path, []byte("{}"), 0600); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected file to exist: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestPipe_Local(t *testing.T) {
+	exec := NewLocalExecutor()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "copy.txt")
+
+	err := Pipe(
+		func(w io.Writer) error { return exec.Stream(ctx, "seq 1 100000", nil, w) },
+		func(r io.Reader) error { return exec.Stream(ctx, "cat > "+path, r, nil) },
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "1\n2\n") || !strings.HasSuffix(string(data), "\n100000\n") {
+		t.Fatalf("unexpected copy: %d bytes", len(data))
+	}
+}
+
+func TestPipe_Errors(t *testing.T) {
+	exec := NewLocalExecutor()
+	ctx := context.Background()
+
+	// The sending side fails
+	err := Pipe(
+		func(w io.Writer) error { return exec.Stream(ctx, "echo partial; exit 3", nil, w) },
+		func(r io.Reader) error { return exec.Stream(ctx, "cat > /dev/null", r, nil) },
+	)
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Fatalf("expected the sender's error, got %v", err)
+	}
+
+	// The receiving side stops early; the sender must not hang
+	err = Pipe(
+		func(w io.Writer) error { return exec.Stream(ctx, "yes", nil, w) },
+		func(r io.Reader) error { return exec.Stream(ctx, "head -c 10 >/dev/null; exit 4", r, nil) },
+	)
+	if err == nil || !strings.Contains(err.Error(), "exit status 4") {
+		t.Fatalf("expected the receiver's error, got %v", err)
+	}
+}
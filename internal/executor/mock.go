@@ -3,6 +3,7 @@ package executor
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 )
 
@@ -18,6 +19,8 @@ type MockExecutor struct {
 	Files       map[string][]byte
 	ReadErrors  map[string]error
 	WriteErrors map[string]error
+	// Stdin holds what was streamed into each Stream command
+	Stdin map[string][]byte
 }
 
 func NewMockExecutor() *MockExecutor {
@@ -27,6 +30,7 @@ func NewMockExecutor() *MockExecutor {
 		Files:       make(map[string][]byte),
 		ReadErrors:  make(map[string]error),
 		WriteErrors: make(map[string]error),
+		Stdin:       make(map[string][]byte),
 	}
 }
 
@@ -41,6 +45,28 @@ func (m *MockExecutor) Run(_ context.Context, cmd string) (string, error) {
 	return "", nil
 }
 
+// Stream behaves like Run, writing RunOutputs[cmd] to stdout and keeping
+// whatever stdin supplies in Stdin[cmd].
+func (m *MockExecutor) Stream(_ context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+	m.Calls = append(m.Calls, MockCall{Method: "Stream", Args: []interface{}{cmd}})
+	if err, ok := m.RunErrors[cmd]; ok {
+		return err
+	}
+	if stdin != nil {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		m.Stdin[cmd] = data
+	}
+	if stdout != nil {
+		if _, err := io.WriteString(stdout, m.RunOutputs[cmd]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *MockExecutor) WriteFile(_ context.Context, path string, content []byte, mode os.FileMode) error {
 	m.Calls = append(m.Calls, MockCall{Method: "WriteFile", Args: []interface{}{path, content, mode}})
 	if err, ok := m.WriteErrors[path]; ok {
@@ -58,5 +84,5 @@ func (m *MockExecutor) ReadFile(_ context.Context, path string) ([]byte, error)
 	if data, ok := m.Files[path]; ok {
 		return data, nil
 	}
-	return nil, fmt.Errorf("file not found: %s", path)
+	return nil, fmt.Errorf("file not found: %s: %w", path, os.ErrNotExist)
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -103,6 +104,24 @@ func (r *RemoteExecutor) Run(_ context.Context, cmd string) (string, error) {
 	return stdout.String(), nil
 }
 
+func (r *RemoteExecutor) Stream(_ context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+	session, err := r.client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = &stderr
+
+	if err := session.Run(r.wrapCmd(cmd)); err != nil {
+		return fmt.Errorf("%w: %s", err, stderr.String())
+	}
+	return nil
+}
+
 func (r *RemoteExecutor) WriteFile(_ context.Context, path string, content []byte, mode os.FileMode) error {
 	session, err := r.client.NewSession()
 	if err != nil {
@@ -136,6 +155,10 @@ func (r *RemoteExecutor) ReadFile(_ context.Context, path string) ([]byte, error
 	session.Stderr = &stderr
 
 	if err := session.Run(r.wrapCmd(fmt.Sprintf("cat %s", path))); err != nil {
+		// Let callers tell a missing file from a failed read via os.ErrNotExist
+		if strings.Contains(stderr.String(), "No such file or directory") {
+			return nil, fmt.Errorf("failed to read %s: %w", path, os.ErrNotExist)
+		}
 		return nil, fmt.Errorf("failed to read %s: %w: %s", path, err, stderr.String())
 	}
 	return stdout.Bytes(), nil
@@ -3,6 +3,7 @@ package hardening
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/pankajbeniwal/bunkr/internal/executor"
 )
@@ -35,3 +36,42 @@ func FirewallStep(sshPort int) Step {
 		},
 	}
 }
+
+// OpenPort allows inbound traffic on a port published directly by an app.
+func OpenPort(ctx context.Context, exec executor.Executor, port int, proto string) error {
+	if _, err := exec.Run(ctx, fmt.Sprintf("ufw allow %d/%s", port, proto)); err != nil {
+		return fmt.Errorf("failed to open port %d/%s: %w", port, proto, err)
+	}
+	return nil
+}
+
+// ClosePort removes a rule added by OpenPort.
+func ClosePort(ctx context.Context, exec executor.Executor, port int, proto string) error {
+	if _, err := exec.Run(ctx, fmt.Sprintf("ufw delete allow %d/%s", port, proto)); err != nil {
+		return fmt.Errorf("failed to close port %d/%s: %w", port, proto, err)
+	}
+	return nil
+}
+
+// AllowedPorts returns the "port/proto" rules ufw currently allows, e.g.
+// "5432/tcp". An inactive or missing ufw allows nothing.
+func AllowedPorts(ctx context.Context, exec executor.Executor) (map[string]bool, error) {
+	allowed := make(map[string]bool)
+	out, err := exec.Run(ctx, "ufw status 2>/dev/null || true")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read firewall rules: %w", err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.Contains(fields[0], "/") {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if f == "ALLOW" {
+				allowed[fields[0]] = true
+				break
+			}
+		}
+	}
+	return allowed, nil
+}
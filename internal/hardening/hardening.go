@@ -2,6 +2,8 @@ package hardening
 
 import (
 	"context"
+	"strconv"
+	"strings"
 
 	"github.com/pankajbeniwal/bunkr/internal/executor"
 	"github.com/pankajbeniwal/bunkr/internal/state"
@@ -38,12 +40,8 @@ func Run(ctx context.Context, exec executor.Executor, s *state.State, sshPort in
 	var results []StepResult
 
 	for _, step := range steps {
-		if s.Hardening.Steps[step.Name] {
-			ui.Skip(step.Label + " — already configured")
-			results = append(results, StepResult{Name: step.Name, Skipped: true})
-			continue
-		}
-
+		// Steps recorded in state are checked again, since a package
+		// upgrade or a manual edit can undo them
 		applied, err := step.Check(ctx, exec)
 		if err == nil && applied {
 			ui.Skip(step.Label + " — already configured")
@@ -51,6 +49,9 @@ func Run(ctx context.Context, exec executor.Executor, s *state.State, sshPort in
 			results = append(results, StepResult{Name: step.Name, Skipped: true})
 			continue
 		}
+		if s.Hardening.Steps[step.Name] {
+			ui.Warn(step.Label + " — no longer in place, applying again")
+		}
 
 		if err := step.Apply(ctx, exec); err != nil {
 			ui.Error(step.Label + " failed: " + err.Error())
@@ -66,3 +67,28 @@ func Run(ctx context.Context, exec executor.Executor, s *state.State, sshPort in
 	s.Hardening.Applied = true
 	return results, nil
 }
+
+// Detect reports which hardening steps are in place without changing
+// anything, along with the SSH port from bunkr's sshd drop-in.
+func Detect(ctx context.Context, exec executor.Executor) state.HardeningState {
+	h := state.HardeningState{Steps: make(map[string]bool)}
+	applied := true
+	for _, step := range Steps(0) {
+		ok, err := step.Check(ctx, exec)
+		if err == nil && ok {
+			h.Steps[step.Name] = true
+		} else {
+			applied = false
+		}
+	}
+	h.Applied = applied
+
+	if data, err := exec.ReadFile(ctx, sshConfigPath); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if port, ok := strings.CutPrefix(strings.TrimSpace(line), "Port "); ok {
+				h.SSHPort, _ = strconv.Atoi(port)
+			}
+		}
+	}
+	return h
+}
@@ -55,3 +55,70 @@ func TestRunSteps_AllSkipped(t *testing.T) {
 		}
 	}
 }
+
+func TestRunSteps_ReappliesRecordedStep(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	s := state.New()
+	s.Hardening.Applied = true
+	for _, step := range Steps(2222) {
+		s.Hardening.Steps[step.Name] = true
+	}
+	// Recorded as applied, but something removed fail2ban since
+	mock.RunErrors["systemctl is-active fail2ban"] = fmt.Errorf("inactive")
+
+	results, err := Run(context.Background(), mock, s, 2222)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range results {
+		if r.Skipped == (r.Name == "fail2ban") {
+			t.Fatalf("unexpected result for step %s: skipped=%v", r.Name, r.Skipped)
+		}
+	}
+	installed := false
+	for _, c := range mock.Calls {
+		if c.Method == "Run" && c.Args[0] == "apt-get install -y fail2ban" {
+			installed = true
+		}
+	}
+	if !installed {
+		t.Fatal("expected fail2ban to be installed again")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunErrors["swapon --show | grep -q /"] = fmt.Errorf("no swap")
+	mock.Files["/etc/ssh/sshd_config.d/99-bunkr.conf"] = []byte("Port 2222\nPermitRootLogin no\n# bunkr-managed")
+
+	h := Detect(context.Background(), mock)
+	if h.Applied {
+		t.Fatal("expected hardening to be incomplete without swap")
+	}
+	if len(h.Steps) != 6 || h.Steps["swap"] || !h.Steps["ssh_hardening"] {
+		t.Fatalf("unexpected steps: %v", h.Steps)
+	}
+	if h.SSHPort != 2222 {
+		t.Fatalf("expected SSH port 2222, got %d", h.SSHPort)
+	}
+}
+
+func TestAllowedPorts(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunOutputs["ufw status 2>/dev/null || true"] = `Status: active
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW       Anywhere
+5432/tcp                   ALLOW       Anywhere
+8080/tcp                   DENY        Anywhere
+5432/tcp (v6)              ALLOW       Anywhere (v6)
+`
+	allowed, err := AllowedPorts(context.Background(), mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !allowed["22/tcp"] || !allowed["5432/tcp"] || allowed["8080/tcp"] {
+		t.Fatalf("unexpected rules: %v", allowed)
+	}
+}
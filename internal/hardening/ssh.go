@@ -7,12 +7,14 @@ import (
 	"github.com/pankajbeniwal/bunkr/internal/executor"
 )
 
+const sshConfigPath = "/etc/ssh/sshd_config.d/99-bunkr.conf"
+
 func SSHStep(port int) Step {
 	return Step{
 		Name:  "ssh_hardening",
 		Label: "SSH hardened",
 		Check: func(ctx context.Context, exec executor.Executor) (bool, error) {
-			_, err := exec.Run(ctx, "test -f /etc/ssh/sshd_config.d/99-bunkr.conf")
+			_, err := exec.Run(ctx, "test -f "+sshConfigPath)
 			return err == nil, err
 		},
 		Apply: func(ctx context.Context, exec executor.Executor) error {
@@ -31,13 +33,13 @@ MaxAuthTries 3
 AllowUsers bunkr
 # bunkr-managed`, port)
 
-			if err := exec.WriteFile(ctx, "/etc/ssh/sshd_config.d/99-bunkr.conf", []byte(config), 0644); err != nil {
+			if err := exec.WriteFile(ctx, sshConfigPath, []byte(config), 0644); err != nil {
 				return err
 			}
 
 			// Validate config before restarting
 			if _, err := exec.Run(ctx, "sshd -t"); err != nil {
-				exec.Run(ctx, "rm -f /etc/ssh/sshd_config.d/99-bunkr.conf")
+				exec.Run(ctx, "rm -f "+sshConfigPath)
 				return fmt.Errorf("invalid SSH config: %w", err)
 			}
 
@@ -57,7 +59,7 @@ ListenStream=[::]:%d`, port, port)
 				if _, err := exec.Run(ctx, "systemctl daemon-reload && systemctl restart ssh.socket && systemctl restart ssh"); err != nil {
 					// Clean up on failure
 					exec.Run(ctx, "rm -rf /etc/systemd/system/ssh.socket.d")
-					exec.Run(ctx, "rm -f /etc/ssh/sshd_config.d/99-bunkr.conf")
+					exec.Run(ctx, "rm -f "+sshConfigPath)
 					exec.Run(ctx, "systemctl daemon-reload && systemctl restart ssh.socket && systemctl restart ssh")
 					return err
 				}
@@ -71,7 +73,7 @@ ListenStream=[::]:%d`, port, port)
 			// Verify SSH is listening on the new port
 			if _, err := exec.Run(ctx, fmt.Sprintf("ss -tlnp | grep ':%d '", port)); err != nil {
 				// Restore on failure
-				exec.Run(ctx, "rm -f /etc/ssh/sshd_config.d/99-bunkr.conf")
+				exec.Run(ctx, "rm -f "+sshConfigPath)
 				exec.Run(ctx, "rm -rf /etc/systemd/system/ssh.socket.d")
 				exec.Run(ctx, "systemctl daemon-reload && (systemctl restart ssh.socket 2>/dev/null; systemctl restart sshd 2>/dev/null || systemctl restart ssh)")
 				return fmt.Errorf("SSH not listening on port %d after restart, restored backup", port)
@@ -0,0 +1,174 @@
+// Package inventory keeps the local list of servers bunkr manages, so
+// commands can take --on <name> instead of a full user@host:port target.
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Server is one inventory entry. Apps lists what bunkr installed there.
+type Server struct {
+	User string   `yaml:"user"`
+	Host string   `yaml:"host"`
+	Port int      `yaml:"port"`
+	Apps []string `yaml:"apps,omitempty"`
+}
+
+// Target returns the server as a user@host:port string for --on.
+func (s Server) Target() string {
+	return s.User + "@" + net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// Inventory is the contents of servers.yaml.
+type Inventory struct {
+	Default string             `yaml:"default,omitempty"`
+	Servers map[string]*Server `yaml:"servers"`
+}
+
+// Path returns where the inventory is kept: $XDG_CONFIG_HOME/bunkr or
+// ~/.config/bunkr, on every OS.
+func Path() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "bunkr", "servers.yaml"), nil
+}
+
+// Load reads the inventory at path. A missing file is an empty inventory.
+func Load(path string) (*Inventory, error) {
+	inv := &Inventory{Servers: make(map[string]*Server)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return inv, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, inv); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if inv.Servers == nil {
+		inv.Servers = make(map[string]*Server)
+	}
+	for name, s := range inv.Servers {
+		if s == nil || s.Host == "" {
+			return nil, fmt.Errorf("failed to parse %s: server %s has no host", path, name)
+		}
+		if s.User == "" {
+			s.User = "root"
+		}
+		if s.Port == 0 {
+			s.Port = 22
+		}
+	}
+	return inv, nil
+}
+
+// Save writes the inventory to path, creating its directory.
+func Save(path string, inv *Inventory) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	data, err := yaml.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("failed to encode inventory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// ParseTarget reads a user@host[:port] target. User defaults to root and
+// port to 22, as for --on.
+func ParseTarget(target string) (Server, error) {
+	s := Server{User: "root", Port: 22}
+	hostport := target
+	if user, rest, ok := strings.Cut(target, "@"); ok {
+		s.User, hostport = user, rest
+	}
+	s.Host = hostport
+	if host, port, err := net.SplitHostPort(hostport); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return Server{}, fmt.Errorf("invalid port in %q", target)
+		}
+		s.Host, s.Port = host, p
+	}
+	s.Host = strings.TrimSuffix(strings.TrimPrefix(s.Host, "["), "]")
+	if s.User == "" || s.Host == "" {
+		return Server{}, fmt.Errorf("invalid target %q, expected user@host[:port]", target)
+	}
+	return s, nil
+}
+
+// Names returns the server names in sorted order.
+func (inv *Inventory) Names() []string {
+	names := make([]string, 0, len(inv.Servers))
+	for name := range inv.Servers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Resolve turns an --on value into a target. A server name resolves to its
+// entry, an empty value to the default server, and anything else is taken
+// as a target and matched to an entry by host. name is "" when no entry
+// matches; target is "" when on is empty and there is no default.
+func (inv *Inventory) Resolve(on string) (target, name string, err error) {
+	if on == "" {
+		if inv.Default == "" {
+			return "", "", nil
+		}
+		s, ok := inv.Servers[inv.Default]
+		if !ok {
+			return "", "", fmt.Errorf("default server %s is not in the inventory", inv.Default)
+		}
+		return s.Target(), inv.Default, nil
+	}
+	if s, ok := inv.Servers[on]; ok {
+		return s.Target(), on, nil
+	}
+	return on, inv.FindHost(on), nil
+}
+
+// FindHost returns the name of the entry for target's host, "" if none.
+func (inv *Inventory) FindHost(target string) string {
+	parsed, err := ParseTarget(target)
+	if err != nil {
+		return ""
+	}
+	for _, name := range inv.Names() {
+		if inv.Servers[name].Host == parsed.Host {
+			return name
+		}
+	}
+	return ""
+}
+
+// Remove deletes a server, clearing the default if it pointed there.
+func (inv *Inventory) Remove(name string) error {
+	if _, ok := inv.Servers[name]; !ok {
+		return fmt.Errorf("server %s not found", name)
+	}
+	delete(inv.Servers, name)
+	if inv.Default == name {
+		inv.Default = ""
+	}
+	return nil
+}
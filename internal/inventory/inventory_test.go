@@ -0,0 +1,123 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"root@167.71.50.23", "root@167.71.50.23:22"},
+		{"bunkr@167.71.50.23:2222", "bunkr@167.71.50.23:2222"},
+		{"example.com", "root@example.com:22"},
+		{"root@[2001:db8::1]", "root@[2001:db8::1]:22"},
+		{"bunkr@[2001:db8::1]:2222", "bunkr@[2001:db8::1]:2222"},
+	}
+	for _, tt := range tests {
+		s, err := ParseTarget(tt.input)
+		if err != nil {
+			t.Errorf("ParseTarget(%q): unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got := s.Target(); got != tt.want {
+			t.Errorf("ParseTarget(%q).Target() = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"root@host:99999", "@host", "root@"} {
+		if _, err := ParseTarget(bad); err == nil {
+			t.Errorf("ParseTarget(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bunkr", "servers.yaml")
+
+	inv, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading a missing file: %v", err)
+	}
+	if len(inv.Servers) != 0 {
+		t.Fatalf("expected an empty inventory, got %+v", inv.Servers)
+	}
+
+	inv.Servers["blog-prod"] = &Server{User: "bunkr", Host: "167.71.50.23", Port: 2222, Apps: []string{"ghost"}}
+	inv.Default = "blog-prod"
+	if err := Save(path, inv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected servers.yaml with mode 0600, got %v (%v)", info, err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv := loaded.Servers["blog-prod"]
+	if loaded.Default != "blog-prod" || srv == nil || srv.Target() != "bunkr@167.71.50.23:2222" || len(srv.Apps) != 1 {
+		t.Fatalf("unexpected inventory after round trip: %+v", loaded)
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "servers.yaml")
+	os.WriteFile(path, []byte("servers:\n  lab:\n    host: 10.0.0.5\n"), 0600)
+
+	inv, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := inv.Servers["lab"].Target(); got != "root@10.0.0.5:22" {
+		t.Fatalf("expected root and port 22 by default, got %s", got)
+	}
+
+	os.WriteFile(path, []byte("servers:\n  lab:\n    user: root\n"), 0600)
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected an error for a server without a host")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	inv := &Inventory{
+		Default: "blog-prod",
+		Servers: map[string]*Server{
+			"blog-prod": {User: "bunkr", Host: "167.71.50.23", Port: 2222},
+			"lab":       {User: "root", Host: "10.0.0.5", Port: 22},
+		},
+	}
+
+	tests := []struct {
+		on, target, name string
+	}{
+		{"", "bunkr@167.71.50.23:2222", "blog-prod"},
+		{"lab", "root@10.0.0.5:22", "lab"},
+		{"root@167.71.50.23", "root@167.71.50.23", "blog-prod"},
+		{"root@203.0.113.9", "root@203.0.113.9", ""},
+	}
+	for _, tt := range tests {
+		target, name, err := inv.Resolve(tt.on)
+		if err != nil {
+			t.Errorf("Resolve(%q): unexpected error: %v", tt.on, err)
+			continue
+		}
+		if target != tt.target || name != tt.name {
+			t.Errorf("Resolve(%q) = %q, %q; want %q, %q", tt.on, target, name, tt.target, tt.name)
+		}
+	}
+
+	if err := inv.Remove("blog-prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target, _, _ := inv.Resolve(""); target != "" {
+		t.Fatalf("expected no target once the default is removed, got %q", target)
+	}
+	if err := inv.Remove("blog-prod"); err == nil {
+		t.Fatal("expected an error removing an unknown server")
+	}
+}
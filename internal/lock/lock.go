@@ -0,0 +1,151 @@
+// Package lock serializes commands that change a server, so two people
+// running bunkr against the same VPS don't overwrite each other's state.json
+// or Caddyfile.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+)
+
+const LockPath = "/etc/bunkr/.lock"
+
+// pollInterval is how often Acquire retries a held lock.
+var pollInterval = 2 * time.Second
+
+// Holder describes who holds the lock. It is stored as JSON in LockPath.
+type Holder struct {
+	ID      string    `json:"id"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Since   time.Time `json:"since"`
+}
+
+func (h Holder) String() string {
+	return fmt.Sprintf("%s@%s running '%s' since %s", h.User, h.Host, h.Command, h.Since.Local().Format("2006-01-02 15:04:05"))
+}
+
+// LockedError is returned when the lock is still held after the timeout.
+type LockedError struct {
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("server is locked by %s", e.Holder)
+}
+
+type Lock struct {
+	exec   executor.Executor
+	holder Holder
+}
+
+// Acquire takes the server lock for holder, waiting up to timeout while
+// someone else holds it. waiting is called once with the current holder
+// when Acquire has to wait. The lock file is created with the shell's
+// noclobber option, which fails if it already exists, so only one caller
+// can win even when several SSH sessions race.
+func Acquire(ctx context.Context, exec executor.Executor, holder Holder, timeout time.Duration, waiting func(Holder)) (*Lock, error) {
+	if holder.ID == "" {
+		b := make([]byte, 8)
+		rand.Read(b)
+		holder.ID = hex.EncodeToString(b)
+	}
+	if holder.Since.IsZero() {
+		holder.Since = time.Now().UTC()
+	}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	notified, retried := false, false
+	for {
+		_, createErr := exec.Run(ctx, createCmd(data))
+		if createErr == nil {
+			return &Lock{exec: exec, holder: holder}, nil
+		}
+
+		current, err := Current(ctx, exec)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			// Released between our attempt and the read, so try again once;
+			// failing twice with no lock in place means we can't create it.
+			if retried {
+				return nil, fmt.Errorf("failed to create lock: %w", createErr)
+			}
+			retried = true
+			continue
+		}
+		retried = false
+		if !time.Now().Before(deadline) {
+			return nil, &LockedError{Holder: *current}
+		}
+		if !notified && waiting != nil {
+			waiting(*current)
+			notified = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release removes the lock if it is still ours. A lock that was forced open
+// and taken by someone else is left alone.
+func (l *Lock) Release(ctx context.Context) error {
+	current, err := Current(ctx, l.exec)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.ID != l.holder.ID {
+		return fmt.Errorf("lock is no longer held by this command")
+	}
+	_, err = l.exec.Run(ctx, "rm -f "+LockPath)
+	return err
+}
+
+// Current returns the lock's holder, or nil when the server is unlocked.
+func Current(ctx context.Context, exec executor.Executor) (*Holder, error) {
+	data, err := exec.ReadFile(ctx, LockPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock: %w", err)
+	}
+	var h Holder
+	if err := json.Unmarshal(data, &h); err != nil {
+		// Still a lock, just one we can't describe
+		return &Holder{User: "unknown", Host: "unknown", Command: "unknown"}, nil
+	}
+	return &h, nil
+}
+
+// ForceUnlock removes the lock whoever holds it.
+func ForceUnlock(ctx context.Context, exec executor.Executor) error {
+	if _, err := exec.Run(ctx, "rm -f "+LockPath); err != nil {
+		return fmt.Errorf("failed to remove lock: %w", err)
+	}
+	return nil
+}
+
+func createCmd(data []byte) string {
+	quoted := "'" + strings.ReplaceAll(string(data), "'", `'\''`) + "'"
+	return fmt.Sprintf("mkdir -p /etc/bunkr && (set -C; printf '%%s' %s > %s)", quoted, LockPath)
+}
@@ -0,0 +1,131 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+)
+
+var (
+	alice = Holder{ID: "a1", User: "alice", Host: "laptop", Command: "install ghost", Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bob   = Holder{ID: "b2", User: "bob", Host: "desktop", Command: "update n8n", Since: time.Date(2026, 1, 1, 0, 5, 0, 0, time.UTC)}
+)
+
+func holderCmd(t *testing.T, h Holder) string {
+	t.Helper()
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return createCmd(data)
+}
+
+func lockedBy(t *testing.T, mock *executor.MockExecutor, h Holder) {
+	t.Helper()
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.Files[LockPath] = data
+}
+
+func TestAcquireAndRelease(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+
+	l, err := Acquire(ctx, mock, alice, time.Second, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd := mock.Calls[0].Args[0].(string)
+	if !strings.Contains(cmd, "set -C") || !strings.Contains(cmd, `"user":"alice"`) {
+		t.Fatalf("expected an exclusive create with the holder, got %s", cmd)
+	}
+
+	lockedBy(t, mock, alice)
+	if err := l.Release(ctx); err != nil {
+		t.Fatalf("unexpected release error: %v", err)
+	}
+	last := mock.Calls[len(mock.Calls)-1]
+	if last.Args[0] != "rm -f "+LockPath {
+		t.Fatalf("expected the lock to be removed, got %v", last.Args[0])
+	}
+}
+
+func TestAcquire_Held(t *testing.T) {
+	pollInterval = time.Millisecond
+	mock := executor.NewMockExecutor()
+	mock.RunErrors[holderCmd(t, alice)] = errors.New("cannot overwrite existing file")
+	lockedBy(t, mock, bob)
+
+	var waitedOn *Holder
+	_, err := Acquire(context.Background(), mock, alice, 10*time.Millisecond, func(h Holder) { waitedOn = &h })
+
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected LockedError, got %v", err)
+	}
+	if locked.Holder.User != "bob" || !strings.Contains(err.Error(), "bob@desktop running 'update n8n'") {
+		t.Fatalf("expected the holder in the error, got %v", err)
+	}
+	if waitedOn == nil || waitedOn.ID != "b2" {
+		t.Fatalf("expected waiting to be called with the holder, got %v", waitedOn)
+	}
+}
+
+func TestAcquire_CreateFails(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunErrors[holderCmd(t, alice)] = errors.New("permission denied")
+
+	_, err := Acquire(context.Background(), mock, alice, time.Second, nil)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected the create error, got %v", err)
+	}
+}
+
+func TestRelease_NotOurs(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+
+	l, err := Acquire(ctx, mock, alice, time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Someone forced the lock open and took it
+	lockedBy(t, mock, bob)
+
+	if err := l.Release(ctx); err == nil {
+		t.Fatal("expected an error releasing someone else's lock")
+	}
+	for _, c := range mock.Calls {
+		if c.Method == "Run" && c.Args[0] == "rm -f "+LockPath {
+			t.Fatal("expected bob's lock to be left in place")
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+
+	h, err := Current(ctx, mock)
+	if err != nil || h != nil {
+		t.Fatalf("expected no holder, got %v, %v", h, err)
+	}
+
+	mock.Files[LockPath] = []byte("garbage")
+	h, err = Current(ctx, mock)
+	if err != nil || h == nil {
+		t.Fatalf("expected an unreadable lock to still count, got %v, %v", h, err)
+	}
+
+	mock.ReadErrors[LockPath] = errors.New("connection reset")
+	if _, err := Current(ctx, mock); err == nil {
+		t.Fatal("expected a read error")
+	}
+}
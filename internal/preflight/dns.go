@@ -0,0 +1,126 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+)
+
+// Resolver looks up A and AAAA records, following CNAMEs. *net.Resolver
+// satisfies it; tests pass a fake so they run offline.
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// publicIPsCmd lists the server's own addresses plus the addresses it is
+// seen from, which differ behind 1:1 NAT (e.g. AWS, GCP).
+const publicIPsCmd = "hostname -I 2>/dev/null; " +
+	"curl -4 -fsS --max-time 5 https://api.ipify.org 2>/dev/null; echo; " +
+	"curl -6 -fsS --max-time 5 https://api6.ipify.org 2>/dev/null; true"
+
+// cgnat is the shared address space used by Tailscale and carrier NAT.
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// PublicIPs returns the server's public addresses, discovered over exec.
+func PublicIPs(ctx context.Context, exec executor.Executor) ([]net.IP, error) {
+	out, err := exec.Run(ctx, publicIPsCmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover server IPs: %w", err)
+	}
+
+	var ips []net.IP
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(out) {
+		ip := net.ParseIP(field)
+		if ip == nil || !isPublic(ip) || seen[ip.String()] {
+			continue
+		}
+		seen[ip.String()] = true
+		ips = append(ips, ip)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("server has no public IP address")
+	}
+	return ips, nil
+}
+
+func isPublic(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !cgnat.Contains(ip)
+}
+
+// CheckDNS resolves domain and passes when every address it resolves to
+// belongs to the server. A mismatch is only a warning: proxied DNS such as
+// Cloudflare's never points at the server directly.
+func CheckDNS(ctx context.Context, resolver Resolver, app, domain string, server []net.IP) Result {
+	res := Result{App: app, Check: "dns " + domain, Domain: domain}
+	addrs, err := resolver.LookupIP(ctx, "ip", domain)
+	if err != nil || len(addrs) == 0 {
+		res.Status = Warn
+		res.Detail = "no A/AAAA record yet"
+		return res
+	}
+
+	ours := make(map[string]bool, len(server))
+	for _, ip := range server {
+		ours[ip.String()] = true
+	}
+	var foreign []string
+	for _, ip := range addrs {
+		if !ours[ip.String()] {
+			foreign = append(foreign, ip.String())
+		}
+	}
+	if len(foreign) > 0 {
+		sort.Strings(foreign)
+		res.Status = Warn
+		res.Detail = fmt.Sprintf("resolves to %s, not this server (%s)", strings.Join(foreign, ", "), joinIPs(server))
+		return res
+	}
+	res.Detail = "points at " + joinIPs(addrs)
+	return res
+}
+
+// CheckDomains resolves every public domain of apps and compares it with
+// the server's public IPs, which are discovered once.
+func CheckDomains(ctx context.Context, exec executor.Executor, resolver Resolver, apps []App) []Result {
+	var results []Result
+	var server []net.IP
+	for _, app := range apps {
+		if app.Recipe.Private {
+			continue
+		}
+		for _, domain := range domains(app.Endpoints) {
+			if server == nil {
+				var err error
+				if server, err = PublicIPs(ctx, exec); err != nil {
+					return append(results, Result{App: "server", Check: "public ip", Status: Warn, Detail: err.Error() + ", skipping DNS checks"})
+				}
+			}
+			results = append(results, CheckDNS(ctx, resolver, app.Recipe.Name, domain, server))
+		}
+	}
+	return results
+}
+
+// PendingDNS returns the domains whose DNS check did not pass.
+func PendingDNS(results []Result) []string {
+	var domains []string
+	for _, r := range results {
+		if r.Domain != "" && r.Status != Pass {
+			domains = append(domains, r.Domain)
+		}
+	}
+	return domains
+}
+
+func joinIPs(ips []net.IP) string {
+	s := make([]string, len(ips))
+	for i, ip := range ips {
+		s[i] = ip.String()
+	}
+	return strings.Join(s, ", ")
+}
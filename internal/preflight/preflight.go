@@ -0,0 +1,259 @@
+// Package preflight checks that a server can run a set of apps before
+// install changes anything on it.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+)
+
+type Status int
+
+const (
+	Pass Status = iota
+	Warn
+	Fail
+)
+
+func (s Status) String() string {
+	switch s {
+	case Warn:
+		return "warn"
+	case Fail:
+		return "fail"
+	}
+	return "pass"
+}
+
+// MarshalText lets reports encode a status as "pass", "warn" or "fail".
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Result is one row of the preflight report.
+type Result struct {
+	App    string
+	Check  string
+	Status Status
+	Detail string
+	Domain string // set on DNS checks so callers can poll them
+}
+
+// App is an app about to be installed, with the endpoints it has been
+// allocated. Their host ports were picked free by state.ScanPorts, so only
+// their domains are checked.
+type App struct {
+	Recipe    *recipe.Recipe
+	Endpoints []state.EndpointState
+}
+
+// Host holds the server facts the checks compare against.
+type Host struct {
+	Arch     string
+	Memory   int64 // total RAM in bytes
+	FreeDisk int64 // free bytes where Docker stores images and volumes
+}
+
+// memoryTolerance lets a "1g" recipe pass as a warning on a 1GB VPS, whose
+// usable RAM is always reported a little below the nominal size.
+const memoryTolerance = 0.85
+
+const (
+	archCmd   = "uname -m"
+	memoryCmd = "awk '/^MemTotal:/ {print $2}' /proc/meminfo"
+	diskCmd   = "df -Pk $(test -d /var/lib/docker && echo /var/lib/docker || echo /) | awk 'NR==2 {print $4}'"
+)
+
+// Inspect reads architecture, memory and free disk from the server.
+func Inspect(ctx context.Context, exec executor.Executor) (Host, error) {
+	var h Host
+	out, err := exec.Run(ctx, archCmd)
+	if err != nil {
+		return h, fmt.Errorf("failed to read architecture: %w", err)
+	}
+	h.Arch = strings.TrimSpace(out)
+
+	out, err = exec.Run(ctx, memoryCmd)
+	if err != nil {
+		return h, fmt.Errorf("failed to read memory: %w", err)
+	}
+	kb, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return h, fmt.Errorf("failed to parse memory %q: %w", strings.TrimSpace(out), err)
+	}
+	h.Memory = kb * 1024
+
+	out, err = exec.Run(ctx, diskCmd)
+	if err != nil {
+		return h, fmt.Errorf("failed to read free disk: %w", err)
+	}
+	kb, err = strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return h, fmt.Errorf("failed to parse free disk %q: %w", strings.TrimSpace(out), err)
+	}
+	h.FreeDisk = kb * 1024
+	return h, nil
+}
+
+// Run checks every app against the server and returns one result per check.
+// It only reads from the server. DNS is checked separately by CheckDomains.
+func Run(ctx context.Context, exec executor.Executor, s *state.State, apps []App) ([]Result, error) {
+	host, err := Inspect(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := caddy.SiteOwners(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	var totalMemory, totalDisk int64
+	for _, app := range apps {
+		r := app.Recipe
+		req := r.Requirements
+		results = append(results, checkArch(r.Name, req, host.Arch))
+
+		if req.Memory != "" {
+			need, _ := recipe.ParseSize(req.Memory)
+			totalMemory += need
+			results = append(results, checkMemory(r.Name, need, host.Memory))
+		}
+		if req.Disk != "" {
+			need, _ := recipe.ParseSize(req.Disk)
+			totalDisk += need
+			results = append(results, checkDisk(r.Name, need, host.FreeDisk))
+		}
+		for _, feature := range req.Kernel {
+			results = append(results, checkKernel(ctx, exec, r.Name, feature))
+		}
+
+		results = append(results, checkAppDir(ctx, exec, s, r.Name))
+		if !r.Private {
+			results = append(results, checkSites(r.Name, app.Endpoints, owners)...)
+		}
+	}
+
+	// Each app may fit on its own but not together
+	if len(apps) > 1 {
+		if totalMemory > 0 {
+			results = append(results, checkMemory("all", totalMemory, host.Memory))
+		}
+		if totalDisk > 0 {
+			results = append(results, checkDisk("all", totalDisk, host.FreeDisk))
+		}
+	}
+	return results, nil
+}
+
+// Failed reports whether any check failed.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func checkArch(app string, req recipe.Requirements, arch string) Result {
+	res := Result{App: app, Check: "architecture", Detail: arch}
+	if !req.SupportsArch(arch) {
+		res.Status = Fail
+		res.Detail = fmt.Sprintf("%s not supported (needs %s)", arch, strings.Join(req.Architectures, ", "))
+	}
+	return res
+}
+
+func checkMemory(app string, need, have int64) Result {
+	res := Result{App: app, Check: "memory", Detail: fmt.Sprintf("%s total, %s required", formatSize(have), formatSize(need))}
+	switch {
+	case have >= need:
+	case float64(have) >= float64(need)*memoryTolerance:
+		res.Status = Warn
+	default:
+		res.Status = Fail
+	}
+	return res
+}
+
+func checkDisk(app string, need, have int64) Result {
+	res := Result{App: app, Check: "disk", Detail: fmt.Sprintf("%s free, %s required", formatSize(have), formatSize(need))}
+	if have < need {
+		res.Status = Fail
+	}
+	return res
+}
+
+// checkKernel looks for a feature as a filesystem, a loaded module or a
+// module that can be loaded.
+func checkKernel(ctx context.Context, exec executor.Executor, app, feature string) Result {
+	res := Result{App: app, Check: "kernel " + feature, Detail: "available"}
+	cmd := fmt.Sprintf("grep -qw %[1]s /proc/filesystems || test -d /sys/module/%[1]s || modinfo %[1]s >/dev/null 2>&1", feature)
+	if _, err := exec.Run(ctx, cmd); err != nil {
+		res.Status = Fail
+		res.Detail = "not available"
+	}
+	return res
+}
+
+func checkAppDir(ctx context.Context, exec executor.Executor, s *state.State, app string) Result {
+	dir := "/opt/bunkr/" + app
+	res := Result{App: app, Check: "app directory", Detail: dir + " is free"}
+	if _, ok := s.Recipes[app]; ok {
+		res.Status = Fail
+		res.Detail = "already installed — run bunkr update or uninstall first"
+		return res
+	}
+	if _, err := exec.Run(ctx, "test -e "+dir); err == nil {
+		res.Status = Warn
+		res.Detail = dir + " exists and will be overwritten"
+	}
+	return res
+}
+
+func checkSites(app string, endpoints []state.EndpointState, owners map[string]string) []Result {
+	var results []Result
+	for _, domain := range domains(endpoints) {
+		res := Result{App: app, Check: "site " + domain, Detail: "not configured in Caddy"}
+		if owner, ok := owners[domain]; ok && owner != app {
+			res.Status = Fail
+			if owner == "" {
+				res.Detail = "already served by a site not managed by bunkr"
+			} else {
+				res.Detail = "already served by " + owner
+			}
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// domains returns each distinct domain served by endpoints, in order.
+func domains(endpoints []state.EndpointState) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, ep := range endpoints {
+		if ep.Domain == "" || seen[ep.Domain] {
+			continue
+		}
+		seen[ep.Domain] = true
+		out = append(out, ep.Domain)
+	}
+	return out
+}
+
+func formatSize(n int64) string {
+	const gib = 1 << 30
+	if n >= gib {
+		return strconv.FormatFloat(float64(n)/gib, 'f', 1, 64) + " GiB"
+	}
+	return strconv.FormatInt(n>>20, 10) + " MiB"
+}
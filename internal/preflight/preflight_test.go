@@ -0,0 +1,230 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+)
+
+// newHost returns a mock server with 1GB of RAM and 20GB free disk.
+func newHost(arch string) *executor.MockExecutor {
+	mock := executor.NewMockExecutor()
+	mock.RunOutputs[archCmd] = arch + "\n"
+	mock.RunOutputs[memoryCmd] = "981234\n"
+	mock.RunOutputs[diskCmd] = "20971520\n"
+	mock.RunErrors["test -e /opt/bunkr/plausible"] = fmt.Errorf("exit status 1")
+	mock.RunErrors["test -e /opt/bunkr/n8n"] = fmt.Errorf("exit status 1")
+	return mock
+}
+
+func find(t *testing.T, results []Result, app, check string) Result {
+	t.Helper()
+	for _, r := range results {
+		if r.App == app && r.Check == check {
+			return r
+		}
+	}
+	t.Fatalf("no %s result for %s in %+v", check, app, results)
+	return Result{}
+}
+
+func TestRun_Requirements(t *testing.T) {
+	mock := newHost("aarch64")
+	mock.RunErrors["grep -qw zfs /proc/filesystems || test -d /sys/module/zfs || modinfo zfs >/dev/null 2>&1"] = fmt.Errorf("exit status 1")
+
+	plausible := &recipe.Recipe{Name: "plausible", Requirements: recipe.Requirements{
+		Memory:        "2g",
+		Disk:          "10g",
+		Architectures: []string{"amd64"},
+		Kernel:        []string{"overlay", "zfs"},
+	}}
+	results, err := Run(context.Background(), mock, state.New(), []App{{Recipe: plausible}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r := find(t, results, "plausible", "architecture"); r.Status != Fail {
+		t.Fatalf("expected arm64 to fail an amd64-only recipe, got %+v", r)
+	}
+	if r := find(t, results, "plausible", "memory"); r.Status != Fail {
+		t.Fatalf("expected 1GB to fail a 2g requirement, got %+v", r)
+	}
+	if r := find(t, results, "plausible", "disk"); r.Status != Pass {
+		t.Fatalf("expected 20GB free to pass, got %+v", r)
+	}
+	if r := find(t, results, "plausible", "kernel overlay"); r.Status != Pass {
+		t.Fatalf("expected overlay to pass, got %+v", r)
+	}
+	if r := find(t, results, "plausible", "kernel zfs"); r.Status != Fail {
+		t.Fatalf("expected zfs to fail, got %+v", r)
+	}
+	if !Failed(results) {
+		t.Fatal("expected Failed to report failures")
+	}
+}
+
+func TestRun_MemoryTolerance(t *testing.T) {
+	mock := newHost("x86_64")
+	n8n := &recipe.Recipe{Name: "n8n", Requirements: recipe.Requirements{Memory: "1g"}}
+
+	results, err := Run(context.Background(), mock, state.New(), []App{{Recipe: n8n}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := find(t, results, "n8n", "memory"); r.Status != Warn {
+		t.Fatalf("expected a 1GB VPS to warn on a 1g requirement, got %+v", r)
+	}
+	if Failed(results) {
+		t.Fatalf("expected no failures, got %+v", results)
+	}
+}
+
+func TestRun_Conflicts(t *testing.T) {
+	mock := newHost("x86_64")
+	delete(mock.RunErrors, "test -e /opt/bunkr/n8n")
+	mock.Files[caddy.CaddyfilePath] = []byte("# bunkr:ghost\nn8n.example.com {\n    reverse_proxy localhost:3000\n}\n# /bunkr:ghost\n")
+
+	s := state.New()
+	s.Recipes["plausible"] = state.RecipeState{Version: "2.1.4"}
+
+	apps := []App{
+		{
+			Recipe:    &recipe.Recipe{Name: "n8n"},
+			Endpoints: []state.EndpointState{{Name: "web", Domain: "n8n.example.com", Port: 3001}},
+		},
+		{
+			Recipe:    &recipe.Recipe{Name: "plausible"},
+			Endpoints: []state.EndpointState{{Name: "web", Domain: "stats.example.com", Port: 3002}},
+		},
+	}
+	results, err := Run(context.Background(), mock, s, apps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r := find(t, results, "n8n", "app directory"); r.Status != Warn {
+		t.Fatalf("expected leftover directory to warn, got %+v", r)
+	}
+	if r := find(t, results, "n8n", "site n8n.example.com"); r.Status != Fail || r.Detail != "already served by ghost" {
+		t.Fatalf("expected domain owned by ghost to fail, got %+v", r)
+	}
+	if r := find(t, results, "plausible", "app directory"); r.Status != Fail {
+		t.Fatalf("expected installed app to fail, got %+v", r)
+	}
+	if r := find(t, results, "plausible", "site stats.example.com"); r.Status != Pass {
+		t.Fatalf("expected unclaimed domain to pass, got %+v", r)
+	}
+}
+
+func TestRun_CombinedMemory(t *testing.T) {
+	mock := newHost("x86_64")
+	mock.RunOutputs[memoryCmd] = "3145728\n" // 3GB
+
+	apps := []App{
+		{Recipe: &recipe.Recipe{Name: "n8n", Requirements: recipe.Requirements{Memory: "1g"}}},
+		{Recipe: &recipe.Recipe{Name: "plausible", Requirements: recipe.Requirements{Memory: "2g", Disk: "30g"}}},
+	}
+	results, err := Run(context.Background(), mock, state.New(), apps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := find(t, results, "all", "memory"); r.Status != Pass {
+		t.Fatalf("expected 3GB to fit 1g+2g, got %+v", r)
+	}
+	if r := find(t, results, "all", "disk"); r.Status != Fail {
+		t.Fatalf("expected 20GB free to fail a 30g total, got %+v", r)
+	}
+}
+
+// fakeResolver answers from a fixed table, already following CNAMEs.
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupIP(_ context.Context, _ string, host string) ([]net.IP, error) {
+	addrs, ok := f[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host")
+	}
+	var ips []net.IP
+	for _, a := range addrs {
+		ips = append(ips, net.ParseIP(a))
+	}
+	return ips, nil
+}
+
+func TestPublicIPs(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunOutputs[publicIPsCmd] = "10.0.0.5 172.17.0.1 100.101.102.103 203.0.113.10 2001:db8::10 fe80::1 \n203.0.113.10\n2001:db8::10\n"
+
+	ips, err := PublicIPs(context.Background(), mock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := joinIPs(ips); got != "203.0.113.10, 2001:db8::10" {
+		t.Fatalf("unexpected public IPs: %s", got)
+	}
+
+	mock.RunOutputs[publicIPsCmd] = "10.0.0.5\n"
+	if _, err := PublicIPs(context.Background(), mock); err == nil {
+		t.Fatal("expected error when the server has no public IP")
+	}
+}
+
+func TestCheckDNS(t *testing.T) {
+	server := []net.IP{net.ParseIP("203.0.113.10"), net.ParseIP("2001:db8::10")}
+	resolver := fakeResolver{
+		"n8n.example.com":   {"203.0.113.10", "2001:db8::10"},
+		"stale.example.com": {"203.0.113.10", "2001:db8::99"},
+		"old.example.com":   {"198.51.100.7"},
+	}
+	ctx := context.Background()
+
+	if r := CheckDNS(ctx, resolver, "n8n", "n8n.example.com", server); r.Status != Pass {
+		t.Fatalf("expected match to pass, got %+v", r)
+	}
+	if r := CheckDNS(ctx, resolver, "n8n", "stale.example.com", server); r.Status != Warn || r.Detail != "resolves to 2001:db8::99, not this server (203.0.113.10, 2001:db8::10)" {
+		t.Fatalf("expected stale AAAA record to warn, got %+v", r)
+	}
+	if r := CheckDNS(ctx, resolver, "n8n", "old.example.com", server); r.Status != Warn {
+		t.Fatalf("expected mismatch to warn, got %+v", r)
+	}
+	if r := CheckDNS(ctx, resolver, "n8n", "new.example.com", server); r.Status != Warn || r.Detail != "no A/AAAA record yet" {
+		t.Fatalf("expected missing record to warn, got %+v", r)
+	}
+}
+
+func TestCheckDomains(t *testing.T) {
+	mock := newHost("x86_64")
+	mock.RunOutputs[publicIPsCmd] = "203.0.113.10\n"
+	resolver := fakeResolver{"n8n.example.com": {"203.0.113.10"}}
+
+	apps := []App{
+		{
+			Recipe: &recipe.Recipe{Name: "n8n"},
+			Endpoints: []state.EndpointState{
+				{Name: "web", Domain: "n8n.example.com", Port: 3001},
+				{Name: "hooks", Domain: "hooks.example.com", Port: 3002},
+			},
+		},
+		{
+			Recipe:    &recipe.Recipe{Name: "openclaw", Private: true},
+			Endpoints: []state.EndpointState{{Name: "web", Domain: "vps.tail1234.ts.net", Port: 3003}},
+		},
+	}
+	results := CheckDomains(context.Background(), mock, resolver, apps)
+	if r := find(t, results, "n8n", "dns n8n.example.com"); r.Status != Pass {
+		t.Fatalf("expected resolved domain to pass, got %+v", r)
+	}
+	if Failed(results) {
+		t.Fatalf("expected DNS mismatches to only warn, got %+v", results)
+	}
+	pending := PendingDNS(results)
+	if len(pending) != 1 || pending[0] != "hooks.example.com" {
+		t.Fatalf("expected only hooks.example.com pending, got %v", pending)
+	}
+}
@@ -9,43 +9,99 @@ import (
 
 type composeFile struct {
 	Services map[string]composeService `yaml:"services"`
+	Networks map[string]composeNetwork `yaml:"networks,omitempty"`
 	Volumes  map[string]interface{}    `yaml:"volumes,omitempty"`
 }
 
+type composeNetwork struct {
+	Name     string `yaml:"name"`
+	Internal bool   `yaml:"internal,omitempty"`
+}
+
+// Every app gets its own bridge network. Backing services live on a second,
+// internal-only network that the primary also joins, so they are reachable
+// from the app but cannot reach the internet unless the recipe opts in.
+const (
+	appNetwork      = "app"
+	internalNetwork = "internal"
+)
+
 type composeService struct {
-	Image       string            `yaml:"image"`
-	Command     string            `yaml:"command,omitempty"`
-	Ports       []string          `yaml:"ports,omitempty"`
-	Volumes     []string          `yaml:"volumes,omitempty"`
-	Environment map[string]string `yaml:"environment,omitempty"`
-	DependsOn   []string          `yaml:"depends_on,omitempty"`
-	Restart     string            `yaml:"restart"`
+	Image       string                       `yaml:"image"`
+	Command     string                       `yaml:"command,omitempty"`
+	Entrypoint  string                       `yaml:"entrypoint,omitempty"`
+	User        string                       `yaml:"user,omitempty"`
+	WorkingDir  string                       `yaml:"working_dir,omitempty"`
+	Ports       []string                     `yaml:"ports,omitempty"`
+	Volumes     []string                     `yaml:"volumes,omitempty"`
+	Tmpfs       []string                     `yaml:"tmpfs,omitempty"`
+	Environment map[string]string            `yaml:"environment,omitempty"`
+	Labels      map[string]string            `yaml:"labels,omitempty"`
+	ExtraHosts  []string                     `yaml:"extra_hosts,omitempty"`
+	ReadOnly    bool                         `yaml:"read_only,omitempty"`
+	SecurityOpt []string                     `yaml:"security_opt,omitempty"`
+	CapDrop     []string                     `yaml:"cap_drop,omitempty"`
+	CapAdd      []string                     `yaml:"cap_add,omitempty"`
+	Ulimits     map[string]Ulimit            `yaml:"ulimits,omitempty"`
+	Networks    []string                     `yaml:"networks,omitempty"`
+	Logging     *composeLogging              `yaml:"logging,omitempty"`
+	HealthCheck *ContainerHealthCheck        `yaml:"healthcheck,omitempty"`
+	DependsOn   map[string]composeDependency `yaml:"depends_on,omitempty"`
+	MemLimit    string                       `yaml:"mem_limit,omitempty"`
+	CPUs        float64                      `yaml:"cpus,omitempty"`
+	PidsLimit   int                          `yaml:"pids_limit,omitempty"`
+	Restart     string                       `yaml:"restart"`
+}
+
+type composeDependency struct {
+	Condition string `yaml:"condition"`
 }
 
-func GenerateCompose(r *Recipe, values map[string]string, hostPort int) ([]byte, error) {
+// GenerateCompose renders the compose file for r. hostPorts holds the host
+// port for each endpoint, in the order returned by EndpointList; endpoints
+// without one are published on their container port.
+func GenerateCompose(r *Recipe, values map[string]string, hostPorts ...int) ([]byte, error) {
 	cf := composeFile{
 		Services: make(map[string]composeService),
-		Volumes:  make(map[string]interface{}),
+		Networks: map[string]composeNetwork{
+			appNetwork: {Name: "bunkr_" + r.Name},
+		},
+		Volumes: make(map[string]interface{}),
 	}
 
 	// Primary service
 	primary := composeService{
-		Image:   r.Image,
-		Command: r.Command,
-		Restart: "unless-stopped",
+		Image:    r.Image,
+		Command:  r.Command,
+		User:     r.User,
+		Tmpfs:    r.Tmpfs,
+		Networks: []string{appNetwork},
+		Restart:  restartPolicy(r.Restart),
 	}
+	primary.applyResources(r.Resources)
+	primary.applySecurity(r.Security)
+	appLogging := defaultLogging.Merge(r.Logging)
+	primary.Logging = appLogging.compose()
 
-	if len(r.Ports) > 0 {
-		primary.Ports = []string{fmt.Sprintf("127.0.0.1:%d:%d", hostPort, r.Ports[0])}
+	for i, ep := range r.EndpointList() {
+		hostPort := ep.Port
+		if i < len(hostPorts) && hostPorts[i] != 0 {
+			hostPort = hostPorts[i]
+		}
+		// HTTP goes through the proxy and TCP through Tailscale for private
+		// apps, so only public TCP endpoints listen on every interface.
+		if ep.IsTCP() && !r.Private {
+			primary.Ports = append(primary.Ports, fmt.Sprintf("%d:%d", hostPort, ep.Port))
+		} else {
+			primary.Ports = append(primary.Ports, fmt.Sprintf("127.0.0.1:%d:%d", hostPort, ep.Port))
+		}
 	}
 
 	if len(r.Volumes) > 0 {
 		primary.Volumes = r.Volumes
-		for _, v := range r.Volumes {
-			volName := strings.Split(v, ":")[0]
-			cf.Volumes[volName] = nil
-		}
+		addNamedVolumes(cf.Volumes, r.Volumes)
 	}
+	primary.Volumes = appendFileMounts(primary.Volumes, r.Files, r.Name, true)
 
 	if len(r.Environment) > 0 {
 		env := make(map[string]string)
@@ -55,12 +111,18 @@ func GenerateCompose(r *Recipe, values map[string]string, hostPort int) ([]byte,
 		primary.Environment = env
 	}
 
-	var dependsOn []string
+	services := make(map[string]Service, len(r.Services))
 	for _, svc := range r.Services {
-		dependsOn = append(dependsOn, svc.Name)
+		services[svc.Name] = svc
 	}
-	if len(dependsOn) > 0 {
-		primary.DependsOn = dependsOn
+
+	if len(r.Services) > 0 {
+		cf.Networks[internalNetwork] = composeNetwork{Name: "bunkr_" + r.Name + "_internal", Internal: true}
+		primary.Networks = append(primary.Networks, internalNetwork)
+		primary.DependsOn = make(map[string]composeDependency, len(r.Services))
+		for _, svc := range r.Services {
+			primary.DependsOn[svc.Name] = composeDependency{Condition: svc.ReadyCondition()}
+		}
 	}
 
 	cf.Services[r.Name] = primary
@@ -68,9 +130,25 @@ func GenerateCompose(r *Recipe, values map[string]string, hostPort int) ([]byte,
 	// Additional services
 	for _, svc := range r.Services {
 		s := composeService{
-			Image:   svc.Image,
-			Restart: "unless-stopped",
+			Image:       svc.Image,
+			Command:     svc.Command,
+			Entrypoint:  svc.Entrypoint,
+			User:        svc.User,
+			WorkingDir:  svc.WorkingDir,
+			Tmpfs:       svc.Tmpfs,
+			Labels:      svc.Labels,
+			ExtraHosts:  svc.ExtraHosts,
+			Ulimits:     svc.Ulimits,
+			HealthCheck: svc.HealthCheck,
+			Networks:    []string{internalNetwork},
+			Restart:     restartPolicy(svc.Restart),
+		}
+		if svc.Egress {
+			s.Networks = append(s.Networks, appNetwork)
 		}
+		s.applyResources(svc.Resources)
+		s.applySecurity(svc.Security)
+		s.Logging = appLogging.Merge(svc.Logging).compose()
 		if len(svc.Environment) > 0 {
 			svcEnv := make(map[string]string, len(svc.Environment))
 			for k, v := range svc.Environment {
@@ -80,9 +158,13 @@ func GenerateCompose(r *Recipe, values map[string]string, hostPort int) ([]byte,
 		}
 		if len(svc.Volumes) > 0 {
 			s.Volumes = svc.Volumes
-			for _, v := range svc.Volumes {
-				volName := strings.Split(v, ":")[0]
-				cf.Volumes[volName] = nil
+			addNamedVolumes(cf.Volumes, svc.Volumes)
+		}
+		s.Volumes = appendFileMounts(s.Volumes, r.Files, svc.Name, false)
+		if len(svc.DependsOn) > 0 {
+			s.DependsOn = make(map[string]composeDependency, len(svc.DependsOn))
+			for _, dep := range svc.DependsOn {
+				s.DependsOn[dep] = composeDependency{Condition: services[dep].ReadyCondition()}
 			}
 		}
 		cf.Services[svc.Name] = s
@@ -99,6 +181,33 @@ func GenerateCompose(r *Recipe, values map[string]string, hostPort int) ([]byte,
 	return data, nil
 }
 
+func (s *composeService) applyResources(res Resources) {
+	s.MemLimit = res.Memory
+	s.CPUs = res.CPUs
+	s.PidsLimit = res.Pids
+}
+
+// addNamedVolumes registers the named volumes among mounts in the top-level
+// volumes section. Bind mounts (host paths) are left alone.
+func addNamedVolumes(volumes map[string]interface{}, mounts []string) {
+	for _, v := range mounts {
+		volName := strings.Split(v, ":")[0]
+		if strings.HasPrefix(volName, "/") || strings.HasPrefix(volName, ".") || strings.HasPrefix(volName, "~") {
+			continue
+		}
+		volumes[volName] = nil
+	}
+}
+
+func appendFileMounts(mounts []string, files []File, service string, primary bool) []string {
+	for _, f := range files {
+		if f.mountedInto(service, primary) {
+			mounts = append(mounts, f.mountSpec())
+		}
+	}
+	return mounts
+}
+
 func expandEnvValue(value string, values map[string]string) string {
 	result := value
 	for k, v := range values {
@@ -3,6 +3,8 @@ package recipe
 import (
 	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestGenerateCompose_Simple(t *testing.T) {
@@ -121,3 +123,129 @@ func TestGenerateCompose_DifferentHostPort(t *testing.T) {
 		t.Fatal("expected port mapping 127.0.0.1:3005:3000")
 	}
 }
+
+func TestGenerateCompose_ServiceOptions(t *testing.T) {
+	r := &Recipe{
+		Name:  "plausible",
+		Image: "plausible:latest",
+		Ports: []int{8000},
+		Services: []Service{
+			{
+				Name:  "plausible_db",
+				Image: "postgres:16-alpine",
+				HealthCheck: &ContainerHealthCheck{
+					Test:     []string{"CMD-SHELL", "pg_isready -U postgres"},
+					Interval: "5s",
+					Retries:  10,
+				},
+			},
+			{
+				Name:       "plausible_events_db",
+				Image:      "clickhouse/clickhouse-server:24.3-alpine",
+				Command:    "--config-file /etc/clickhouse-server/config.xml",
+				User:       "101:101",
+				WorkingDir: "/var/lib/clickhouse",
+				Tmpfs:      []string{"/tmp"},
+				Ulimits:    map[string]Ulimit{"nofile": {Soft: 262144, Hard: 262144}},
+				Labels:     map[string]string{"bunkr.app": "plausible"},
+				ExtraHosts: []string{"host.docker.internal:host-gateway"},
+				Volumes:    []string{"./clickhouse.xml:/etc/clickhouse-server/config.d/bunkr.xml:ro"},
+				DependsOn:  []string{"plausible_db"},
+			},
+		},
+	}
+
+	out, err := GenerateCompose(r, nil, 8000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var cf composeFile
+	if err := yaml.Unmarshal(out, &cf); err != nil {
+		t.Fatalf("generated compose is not valid YAML: %v", err)
+	}
+
+	primary := cf.Services["plausible"]
+	if primary.DependsOn["plausible_db"].Condition != ConditionHealthy {
+		t.Fatalf("expected primary to wait for healthy db, got %+v", primary.DependsOn)
+	}
+	if primary.DependsOn["plausible_events_db"].Condition != ConditionStarted {
+		t.Fatalf("expected primary to wait for started events db, got %+v", primary.DependsOn)
+	}
+
+	db := cf.Services["plausible_db"]
+	if db.HealthCheck == nil || db.HealthCheck.Retries != 10 || db.HealthCheck.Test[1] != "pg_isready -U postgres" {
+		t.Fatalf("unexpected healthcheck: %+v", db.HealthCheck)
+	}
+
+	events := cf.Services["plausible_events_db"]
+	if events.Command == "" || events.User != "101:101" || events.WorkingDir != "/var/lib/clickhouse" {
+		t.Fatalf("unexpected events service: %+v", events)
+	}
+	if events.Ulimits["nofile"].Hard != 262144 {
+		t.Fatalf("expected nofile ulimit, got %+v", events.Ulimits)
+	}
+	if len(events.Tmpfs) != 1 || len(events.ExtraHosts) != 1 || events.Labels["bunkr.app"] != "plausible" {
+		t.Fatalf("unexpected events service: %+v", events)
+	}
+	if events.DependsOn["plausible_db"].Condition != ConditionHealthy {
+		t.Fatalf("expected events db to wait for healthy db, got %+v", events.DependsOn)
+	}
+
+	if _, ok := cf.Volumes["./clickhouse.xml"]; ok {
+		t.Fatal("bind mount should not be declared as a named volume")
+	}
+}
+
+func TestGenerateCompose_Networks(t *testing.T) {
+	r := &Recipe{
+		Name:  "n8n",
+		Image: "n8n:latest",
+		Ports: []int{5678},
+		Services: []Service{
+			{Name: "n8n_db", Image: "postgres:16-alpine"},
+			{Name: "n8n_worker", Image: "n8n:latest", Egress: true},
+		},
+	}
+
+	out, err := GenerateCompose(r, nil, 5678)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cf composeFile
+	if err := yaml.Unmarshal(out, &cf); err != nil {
+		t.Fatalf("generated compose is not valid YAML: %v", err)
+	}
+
+	if cf.Networks["app"].Name != "bunkr_n8n" || cf.Networks["app"].Internal {
+		t.Fatalf("unexpected app network: %+v", cf.Networks["app"])
+	}
+	if cf.Networks["internal"].Name != "bunkr_n8n_internal" || !cf.Networks["internal"].Internal {
+		t.Fatalf("unexpected internal network: %+v", cf.Networks["internal"])
+	}
+	if got := strings.Join(cf.Services["n8n"].Networks, ","); got != "app,internal" {
+		t.Fatalf("expected primary on app and internal networks, got %s", got)
+	}
+	if got := strings.Join(cf.Services["n8n_db"].Networks, ","); got != "internal" {
+		t.Fatalf("expected db on internal network only, got %s", got)
+	}
+	if got := strings.Join(cf.Services["n8n_worker"].Networks, ","); got != "internal,app" {
+		t.Fatalf("expected egress service on both networks, got %s", got)
+	}
+}
+
+func TestGenerateCompose_SingleServiceNetwork(t *testing.T) {
+	r := &Recipe{Name: "ghost", Image: "ghost:6", Ports: []int{2368}}
+
+	out, err := GenerateCompose(r, nil, 2368)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := string(out)
+	if !strings.Contains(s, "name: bunkr_ghost") {
+		t.Fatalf("expected named app network, got:\n%s", s)
+	}
+	if strings.Contains(s, "internal") {
+		t.Fatalf("expected no internal network without backing services, got:\n%s", s)
+	}
+}
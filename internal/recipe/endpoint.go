@@ -0,0 +1,132 @@
+package recipe
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ProtocolHTTP = "http"
+	ProtocolTCP  = "tcp"
+)
+
+// Endpoint is a named container port exposed by the primary service.
+// HTTP endpoints are proxied (Caddy or Tailscale Serve) on their own domain,
+// taken from a prompt, or on a path under the app's main domain. TCP
+// endpoints are published directly on the host.
+type Endpoint struct {
+	Name         string `yaml:"name"`
+	Port         int    `yaml:"port,omitempty"`
+	Protocol     string `yaml:"protocol,omitempty"`
+	DomainPrompt string `yaml:"domain_prompt,omitempty"`
+	Path         string `yaml:"path,omitempty"`
+	StripPath    bool   `yaml:"strip_path,omitempty"`
+	HostPort     int    `yaml:"host_port,omitempty"` // preferred host port for tcp endpoints
+}
+
+func (e Endpoint) IsTCP() bool {
+	return e.Protocol == ProtocolTCP
+}
+
+// DesiredHostPort is the host port to try first when allocating.
+func (e Endpoint) DesiredHostPort() int {
+	if e.HostPort != 0 {
+		return e.HostPort
+	}
+	return e.Port
+}
+
+// EndpointList returns the recipe's endpoints. Recipes that only declare
+// ports get a single http endpoint on the first port, served on DOMAIN.
+func (r *Recipe) EndpointList() []Endpoint {
+	if len(r.Endpoints) > 0 {
+		eps := make([]Endpoint, len(r.Endpoints))
+		for i, e := range r.Endpoints {
+			if e.Protocol == "" {
+				e.Protocol = ProtocolHTTP
+			}
+			eps[i] = e
+		}
+		return eps
+	}
+	if len(r.Ports) == 0 {
+		return nil
+	}
+	return []Endpoint{{Name: "web", Port: r.Ports[0], Protocol: ProtocolHTTP, DomainPrompt: "DOMAIN"}}
+}
+
+// EndpointDomain returns the domain an http endpoint is served on. Path-only
+// endpoints share the domain of the first endpoint that has one.
+func (r *Recipe) EndpointDomain(e Endpoint, values map[string]string) string {
+	if e.IsTCP() {
+		return ""
+	}
+	if e.DomainPrompt != "" {
+		return values[e.DomainPrompt]
+	}
+	for _, other := range r.EndpointList() {
+		if !other.IsTCP() && other.DomainPrompt != "" {
+			return values[other.DomainPrompt]
+		}
+	}
+	return ""
+}
+
+func (r *Recipe) validateEndpoints() error {
+	prompts := make(map[string]bool, len(r.Prompts))
+	for _, p := range r.Prompts {
+		prompts[p.Key] = true
+	}
+
+	names := make(map[string]bool)
+	hasDomain := false
+	root := ""
+	for _, e := range r.Endpoints {
+		if e.Name == "" {
+			return fmt.Errorf("endpoint name is required")
+		}
+		if names[e.Name] {
+			return fmt.Errorf("duplicate endpoint %s", e.Name)
+		}
+		names[e.Name] = true
+		if e.Port <= 0 {
+			return fmt.Errorf("endpoint %s: port is required", e.Name)
+		}
+		switch e.Protocol {
+		case "", ProtocolHTTP:
+			if e.Path != "" && !strings.HasPrefix(e.Path, "/") {
+				return fmt.Errorf("endpoint %s: path must start with /", e.Name)
+			}
+			if e.DomainPrompt != "" {
+				if !prompts[e.DomainPrompt] {
+					return fmt.Errorf("endpoint %s: unknown domain prompt %s", e.Name, e.DomainPrompt)
+				}
+				hasDomain = true
+			} else if e.Path == "" && !r.Private {
+				return fmt.Errorf("endpoint %s: http endpoints need a domain_prompt or path", e.Name)
+			}
+			// A private app is served on one tailnet name, so only one
+			// endpoint can take its root
+			if r.Private && e.Path == "" {
+				if root != "" {
+					return fmt.Errorf("endpoint %s: private apps can only have one http endpoint without a path (%s has none either)", e.Name, root)
+				}
+				root = e.Name
+			}
+		case ProtocolTCP:
+			if e.DomainPrompt != "" || e.Path != "" {
+				return fmt.Errorf("endpoint %s: tcp endpoints cannot have a domain or path", e.Name)
+			}
+		default:
+			return fmt.Errorf("endpoint %s: unknown protocol %q (expected http or tcp)", e.Name, e.Protocol)
+		}
+	}
+	if !hasDomain && !r.Private {
+		for _, e := range r.Endpoints {
+			if !e.IsTCP() {
+				return fmt.Errorf("endpoint %s: path endpoints need another endpoint with a domain_prompt", e.Name)
+			}
+		}
+	}
+	return nil
+}
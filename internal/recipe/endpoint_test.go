@@ -0,0 +1,135 @@
+package recipe
+
+import (
+	"strings"
+	"testing"
+)
+
+const testEndpointsYAML = `
+name: gitea
+version: "1.22.0"
+image: gitea/gitea:1.22.0
+prompts:
+  - key: DOMAIN
+    label: "Domain for Gitea"
+    required: true
+  - key: DOCS_DOMAIN
+    label: "Domain for docs"
+endpoints:
+  - name: web
+    port: 3000
+    domain_prompt: DOMAIN
+  - name: api
+    port: 3001
+    path: /api
+  - name: docs
+    port: 3002
+    domain_prompt: DOCS_DOMAIN
+  - name: ssh
+    port: 22
+    protocol: tcp
+    host_port: 2223
+`
+
+func TestParseRecipe_Endpoints(t *testing.T) {
+	r, err := Parse([]byte(testEndpointsYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	eps := r.EndpointList()
+	if len(eps) != 4 {
+		t.Fatalf("expected 4 endpoints, got %d", len(eps))
+	}
+	if eps[0].Protocol != ProtocolHTTP {
+		t.Fatalf("expected default protocol http, got %s", eps[0].Protocol)
+	}
+	if !eps[3].IsTCP() || eps[3].DesiredHostPort() != 2223 {
+		t.Fatalf("unexpected ssh endpoint: %+v", eps[3])
+	}
+
+	values := map[string]string{"DOMAIN": "git.example.com", "DOCS_DOMAIN": "docs.example.com"}
+	if d := r.EndpointDomain(eps[1], values); d != "git.example.com" {
+		t.Fatalf("expected path endpoint on main domain, got %s", d)
+	}
+	if d := r.EndpointDomain(eps[2], values); d != "docs.example.com" {
+		t.Fatalf("expected docs domain, got %s", d)
+	}
+	if d := r.EndpointDomain(eps[3], values); d != "" {
+		t.Fatalf("expected no domain for tcp endpoint, got %s", d)
+	}
+}
+
+func TestEndpointList_LegacyPorts(t *testing.T) {
+	r := &Recipe{Ports: []int{2368, 9000}}
+	eps := r.EndpointList()
+	if len(eps) != 1 {
+		t.Fatalf("expected 1 endpoint from legacy ports, got %d", len(eps))
+	}
+	if eps[0].Port != 2368 || eps[0].DomainPrompt != "DOMAIN" || eps[0].Protocol != ProtocolHTTP {
+		t.Fatalf("unexpected legacy endpoint: %+v", eps[0])
+	}
+}
+
+func TestValidate_Endpoints(t *testing.T) {
+	cases := []struct {
+		name string
+		eps  []Endpoint
+	}{
+		{"missing port", []Endpoint{{Name: "web", DomainPrompt: "DOMAIN"}}},
+		{"duplicate", []Endpoint{{Name: "web", Port: 1, DomainPrompt: "DOMAIN"}, {Name: "web", Port: 2, DomainPrompt: "DOMAIN"}}},
+		{"bad protocol", []Endpoint{{Name: "web", Port: 1, Protocol: "udp"}}},
+		{"no route", []Endpoint{{Name: "web", Port: 1}}},
+		{"unknown prompt", []Endpoint{{Name: "web", Port: 1, DomainPrompt: "NOPE"}}},
+		{"relative path", []Endpoint{{Name: "web", Port: 1, DomainPrompt: "DOMAIN"}, {Name: "api", Port: 2, Path: "api"}}},
+		{"path only", []Endpoint{{Name: "api", Port: 2, Path: "/api"}}},
+		{"tcp with domain", []Endpoint{{Name: "ssh", Port: 22, Protocol: ProtocolTCP, DomainPrompt: "DOMAIN"}}},
+	}
+	for _, tc := range cases {
+		r := &Recipe{
+			Name: "app", Version: "1", Image: "app",
+			Prompts:   []Prompt{{Key: "DOMAIN"}},
+			Endpoints: tc.eps,
+		}
+		if err := r.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", tc.name)
+		}
+	}
+
+	// Tailscale Serve has one root per node
+	r := &Recipe{
+		Name: "app", Version: "1", Image: "app", Private: true,
+		Endpoints: []Endpoint{{Name: "web", Port: 1}, {Name: "admin", Port: 2}},
+	}
+	if err := r.Validate(); err == nil {
+		t.Fatal("expected two pathless endpoints on a private recipe to be rejected")
+	}
+	r.Endpoints[1].Path = "/admin"
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGenerateCompose_Endpoints(t *testing.T) {
+	r, _ := Parse([]byte(testEndpointsYAML))
+
+	out, err := GenerateCompose(r, nil, 3000, 3005, 3002, 2223)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := string(out)
+	for _, want := range []string{"127.0.0.1:3000:3000", "127.0.0.1:3005:3001", "127.0.0.1:3002:3002", `- "2223:22"`} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("expected port mapping %q, got:\n%s", want, s)
+		}
+	}
+
+	r.Private = true
+	out, _ = GenerateCompose(r, nil, 3000, 3005, 3002, 2223)
+	if !strings.Contains(string(out), "127.0.0.1:2223:22") {
+		t.Fatalf("expected private tcp endpoint bound to loopback, got:\n%s", out)
+	}
+}
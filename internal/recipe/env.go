@@ -22,9 +22,69 @@ func GenerateEnv(values map[string]string) []byte {
 	return []byte(strings.Join(lines, "\n") + "\n")
 }
 
+// ParseEnv reads a .env file as written by GenerateEnv. Blank lines and
+// comments are skipped.
+func ParseEnv(data []byte) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(k)] = v
+	}
+	return values
+}
+
+// ReuseValues prepares r for an update from the values stored at install.
+// Prompt answers and generated secrets carry over, secrets new to r are
+// generated, and prompts without a stored answer are returned to be asked.
+// r.Environment is replaced with the expanded environment.
+func ReuseValues(r *Recipe, stored map[string]string) (answers map[string]string, missing []Prompt) {
+	answers = make(map[string]string)
+	for _, p := range r.Prompts {
+		if v, ok := stored[p.Key]; ok {
+			answers[p.Key] = v
+		} else {
+			missing = append(missing, p)
+		}
+	}
+
+	env := make(map[string]string, len(r.Environment))
+	for k, v := range r.Environment {
+		if isAutoGenerate(v) {
+			if old, ok := stored[k]; ok && old != "" {
+				v = old
+			}
+		}
+		env[k] = v
+	}
+	r.Environment = ExpandAutoGenerate(env)
+	return answers, missing
+}
+
+func isAutoGenerate(v string) bool {
+	return v == "auto_generate_32" || v == "auto_generate_64"
+}
+
+// randRead fills secrets; tests swap in a seeded source.
+var randRead = rand.Read
+
 func ExpandAutoGenerate(env map[string]string) map[string]string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	// Sorted so a seeded source always gives each key the same secret
+	sort.Strings(keys)
+
 	result := make(map[string]string, len(env))
-	for k, v := range env {
+	for _, k := range keys {
+		v := env[k]
 		switch v {
 		case "auto_generate_32":
 			result[k] = randomHex(16)
@@ -50,6 +110,6 @@ func MergeValues(promptValues, envValues map[string]string) map[string]string {
 
 func randomHex(n int) string {
 	b := make([]byte, n)
-	rand.Read(b)
+	randRead(b)
 	return hex.EncodeToString(b)
 }
@@ -67,3 +67,64 @@ func TestExpandAutoGenerate(t *testing.T) {
 		t.Fatalf("expected 'hello', got %s", expanded["NORMAL"])
 	}
 }
+
+func TestParseEnv(t *testing.T) {
+	values := map[string]string{
+		"DOMAIN": "example.com",
+		"URL":    "https://example.com/?a=b",
+		"EMPTY":  "",
+		"SECRET": "abc123",
+	}
+	parsed := ParseEnv(append([]byte("# comment\n\n"), GenerateEnv(values)...))
+	if len(parsed) != len(values) {
+		t.Fatalf("expected %d values, got %v", len(values), parsed)
+	}
+	for k, v := range values {
+		if parsed[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, parsed[k])
+		}
+	}
+}
+
+func TestReuseValues(t *testing.T) {
+	r := &Recipe{
+		Prompts: []Prompt{
+			{Key: "DOMAIN"},
+			{Key: "GENERIC_TIMEZONE"},
+			{Key: "SMTP_HOST"},
+		},
+		Environment: map[string]string{
+			"DB_PASSWORD": "auto_generate_32",
+			"NEW_SECRET":  "auto_generate_64",
+			"NODE_ENV":    "production",
+		},
+	}
+	stored := map[string]string{
+		"DOMAIN":           "n8n.example.com",
+		"GENERIC_TIMEZONE": "Europe/Berlin",
+		"DB_PASSWORD":      "keepme",
+		"NODE_ENV":         "development",
+		"REMOVED":          "gone",
+	}
+
+	answers, missing := ReuseValues(r, stored)
+
+	if answers["DOMAIN"] != "n8n.example.com" || answers["GENERIC_TIMEZONE"] != "Europe/Berlin" {
+		t.Errorf("expected stored answers, got %v", answers)
+	}
+	if len(missing) != 1 || missing[0].Key != "SMTP_HOST" {
+		t.Errorf("expected only SMTP_HOST to be asked, got %v", missing)
+	}
+	if r.Environment["DB_PASSWORD"] != "keepme" {
+		t.Errorf("expected stored secret to carry over, got %q", r.Environment["DB_PASSWORD"])
+	}
+	if len(r.Environment["NEW_SECRET"]) != 64 {
+		t.Errorf("expected new secret to be generated, got %q", r.Environment["NEW_SECRET"])
+	}
+	if r.Environment["NODE_ENV"] != "production" {
+		t.Errorf("expected the recipe's literal value, got %q", r.Environment["NODE_ENV"])
+	}
+	if _, ok := r.Environment["REMOVED"]; ok {
+		t.Error("expected values the recipe dropped to be left out")
+	}
+}
@@ -20,6 +20,16 @@ func BuildRecipeURL(name string, baseURL string) string {
 	return fmt.Sprintf("%s/%s.yaml", base, name)
 }
 
+// BuildVersionedRecipeURL points at a pinned copy of a recipe, kept under
+// recipes/<name>/<version>.yaml.
+func BuildVersionedRecipeURL(name, version, baseURL string) string {
+	base := defaultBaseURL
+	if baseURL != "" {
+		base = strings.TrimRight(baseURL, "/")
+	}
+	return fmt.Sprintf("%s/%s/%s.yaml", base, name, version)
+}
+
 func BuildIndexURL(baseURL string) string {
 	base := defaultBaseURL
 	if baseURL != "" {
@@ -33,7 +43,31 @@ func getBaseURL() string {
 }
 
 func Fetch(name string) (*Recipe, error) {
-	url := BuildRecipeURL(name, getBaseURL())
+	return fetchRecipe(name, BuildRecipeURL(name, getBaseURL()))
+}
+
+// FetchVersion fetches a specific version of a recipe, or the latest when
+// version is empty. The current recipe is only pinned once a newer version
+// replaces it, so it is used when it has the version asked for (openclaw's
+// "latest" is never pinned).
+func FetchVersion(name, version string) (*Recipe, error) {
+	if version == "" {
+		return Fetch(name)
+	}
+	r, err := fetchRecipe(name+"@"+version, BuildVersionedRecipeURL(name, version, getBaseURL()))
+	if err != nil {
+		if current, cerr := Fetch(name); cerr == nil && current.Version == version {
+			return current, nil
+		}
+		return nil, err
+	}
+	if r.Version != version {
+		return nil, fmt.Errorf("recipe %s@%s declares version %s", name, version, r.Version)
+	}
+	return r, nil
+}
+
+func fetchRecipe(name, url string) (*Recipe, error) {
 	resp, err := http.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch recipe %s: %w", name, err)
@@ -60,9 +94,63 @@ func Fetch(name string) (*Recipe, error) {
 }
 
 type IndexEntry struct {
-	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	Version     string `yaml:"version"`
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Version     string   `yaml:"version"`
+	Versions    []string `yaml:"versions,omitempty"` // pinned versions, newest first
+	Category    string   `yaml:"category,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+	Homepage    string   `yaml:"homepage,omitempty"`
+	Image       string   `yaml:"image,omitempty"`
+	Private     bool     `yaml:"private,omitempty"`
+	MinMemory   string   `yaml:"min_memory,omitempty"`
+	MinDisk     string   `yaml:"min_disk,omitempty"`
+	Digest      string   `yaml:"digest,omitempty"`    // sha256 of the current recipe file
+	Signature   string   `yaml:"signature,omitempty"` // detached signature of the recipe file
+}
+
+// NewIndexEntry describes r for the recipe index. Versions is left for the
+// caller, which knows which pinned copies exist.
+func NewIndexEntry(r *Recipe) IndexEntry {
+	return IndexEntry{
+		Name:        r.Name,
+		Description: r.Description,
+		Version:     r.Version,
+		Category:    r.Category,
+		Tags:        r.Tags,
+		Homepage:    r.Homepage,
+		Image:       r.Image,
+		Private:     r.Private,
+		MinMemory:   r.Requirements.Memory,
+		MinDisk:     r.Requirements.Disk,
+	}
+}
+
+// HasTag reports whether the entry is tagged with tag or is in that
+// category, ignoring case.
+func (e IndexEntry) HasTag(tag string) bool {
+	if strings.EqualFold(e.Category, tag) {
+		return true
+	}
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether term appears in the entry's name, description,
+// category or tags, ignoring case.
+func (e IndexEntry) Matches(term string) bool {
+	term = strings.ToLower(term)
+	fields := append([]string{e.Name, e.Description, e.Category}, e.Tags...)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), term) {
+			return true
+		}
+	}
+	return false
 }
 
 func FetchIndex() ([]IndexEntry, error) {
@@ -1,6 +1,8 @@
 package recipe
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -27,3 +29,61 @@ func TestBuildIndexURL(t *testing.T) {
 		t.Fatalf("expected %s, got %s", expected, url)
 	}
 }
+
+func TestBuildVersionedRecipeURL(t *testing.T) {
+	url := BuildVersionedRecipeURL("ghost", "6.18.0", "")
+	expected := "https://raw.githubusercontent.com/Pankaj3112/bunkr/main/recipes/ghost/6.18.0.yaml"
+	if url != expected {
+		t.Fatalf("expected %s, got %s", expected, url)
+	}
+}
+
+func TestIndexEntry_Search(t *testing.T) {
+	r := &Recipe{
+		Name:         "plausible",
+		Version:      "2.1.4",
+		Description:  "Privacy-friendly web analytics",
+		Category:     "analytics",
+		Tags:         []string{"privacy", "web"},
+		Image:        "plausible/community-edition:v2.1.4",
+		Requirements: Requirements{Memory: "2g"},
+	}
+	e := NewIndexEntry(r)
+	if e.MinMemory != "2g" || e.Image != r.Image {
+		t.Fatalf("unexpected index entry: %+v", e)
+	}
+
+	if !e.HasTag("Analytics") || !e.HasTag("privacy") {
+		t.Fatal("expected category and tags to match case-insensitively")
+	}
+	if e.HasTag("priv") {
+		t.Fatal("expected tags to match whole words only")
+	}
+	for _, term := range []string{"plaus", "WEB ANALYTICS", "privacy"} {
+		if !e.Matches(term) {
+			t.Fatalf("expected %q to match", term)
+		}
+	}
+	if e.Matches("workflow") {
+		t.Fatal("expected workflow not to match")
+	}
+}
+
+func TestFetchVersion_Current(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("../../recipes")))
+	defer srv.Close()
+	t.Setenv("BUNKR_RECIPES_URL", srv.URL)
+
+	// No recipes/openclaw/latest.yaml, but the current recipe is "latest"
+	r, err := FetchVersion("openclaw", "latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Name != "openclaw" || r.Version != "latest" {
+		t.Fatalf("unexpected recipe %s@%s", r.Name, r.Version)
+	}
+
+	if _, err := FetchVersion("openclaw", "0.0.1"); err == nil {
+		t.Fatal("expected an unknown version to fail")
+	}
+}
@@ -0,0 +1,157 @@
+package recipe
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultFileMode = 0644
+
+// File is a config file rendered from a template and written under
+// /opt/bunkr/<app>/ before the containers start.
+type File struct {
+	Path     string   `yaml:"path"`
+	Content  string   `yaml:"content,omitempty"`
+	Mode     string   `yaml:"mode,omitempty"`
+	Owner    string   `yaml:"owner,omitempty"`
+	Mount    string   `yaml:"mount,omitempty"`
+	ReadOnly bool     `yaml:"read_only,omitempty"`
+	Services []string `yaml:"services,omitempty"`
+}
+
+type RenderedFile struct {
+	Path    string
+	Content []byte
+	Mode    os.FileMode
+	Owner   string
+}
+
+func (f File) fileMode() (os.FileMode, error) {
+	if f.Mode == "" {
+		return defaultFileMode, nil
+	}
+	m, err := strconv.ParseUint(f.Mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("file %s: invalid mode %q", f.Path, f.Mode)
+	}
+	return os.FileMode(m), nil
+}
+
+// mountSpec returns the compose bind mount for f, relative to the app dir.
+func (f File) mountSpec() string {
+	spec := fmt.Sprintf("./%s:%s", path.Clean(f.Path), f.Mount)
+	if f.ReadOnly {
+		spec += ":ro"
+	}
+	return spec
+}
+
+// mountedInto reports whether f should be bind-mounted into the named service.
+// Files without an explicit service list go to the primary service.
+func (f File) mountedInto(service string, primary bool) bool {
+	if f.Mount == "" {
+		return false
+	}
+	if len(f.Services) == 0 {
+		return primary
+	}
+	for _, s := range f.Services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+func (f File) validate() error {
+	if f.Path == "" {
+		return fmt.Errorf("file path is required")
+	}
+	clean := path.Clean(f.Path)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("file %s: path must be relative to the app directory", f.Path)
+	}
+	switch clean {
+	case "docker-compose.yml", ".env":
+		return fmt.Errorf("file %s: path is reserved", f.Path)
+	}
+	if f.Mount != "" && !path.IsAbs(f.Mount) {
+		return fmt.Errorf("file %s: mount must be an absolute container path", f.Path)
+	}
+	_, err := f.fileMode()
+	return err
+}
+
+// volumeAt returns the volume in mounts whose container path contains
+// target, or "" when none does.
+func volumeAt(mounts []string, target string) string {
+	for _, m := range mounts {
+		parts := strings.Split(m, ":")
+		if len(parts) < 2 {
+			continue
+		}
+		if strings.HasPrefix(path.Clean(target), path.Clean(parts[1])+"/") {
+			return parts[0]
+		}
+	}
+	return ""
+}
+
+// RenderFiles expands ${KEY} references in each file's content.
+func RenderFiles(r *Recipe, values map[string]string) ([]RenderedFile, error) {
+	var out []RenderedFile
+	for _, f := range r.Files {
+		mode, err := f.fileMode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, RenderedFile{
+			Path:    path.Clean(f.Path),
+			Content: []byte(expandEnvValue(f.Content, values)),
+			Mode:    mode,
+			Owner:   f.Owner,
+		})
+	}
+	return out, nil
+}
+
+// SecretKeys returns the keys whose values must not be shown: secret prompts
+// and auto-generated environment values. Call it before ReuseValues, which
+// replaces the auto_generate markers with the values themselves.
+func (r *Recipe) SecretKeys() []string {
+	var keys []string
+	for _, p := range r.Prompts {
+		if p.Secret {
+			keys = append(keys, p.Key)
+		}
+	}
+	for k, v := range r.Environment {
+		if isAutoGenerate(v) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MaskSecrets replaces every occurrence of secrets in content with asterisks.
+func MaskSecrets(content []byte, secrets []string) []byte {
+	// Longest first so a secret containing another is masked whole
+	sorted := slices.Clone(secrets)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	var pairs []string
+	for _, secret := range sorted {
+		if secret != "" {
+			pairs = append(pairs, secret, "********")
+		}
+	}
+	if len(pairs) == 0 {
+		return content
+	}
+	return []byte(strings.NewReplacer(pairs...).Replace(string(content)))
+}
@@ -0,0 +1,158 @@
+package recipe
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testFilesYAML = `
+name: openclaw
+version: "1.0.0"
+image: openclaw:latest
+ports:
+  - 18789
+services:
+  - name: openclaw_proxy
+    image: nginx:alpine
+files:
+  - path: config/openclaw.json
+    mode: "0600"
+    owner: "1000:1000"
+    mount: /home/node/.openclaw/openclaw.json
+    content: |
+      {"gateway": {"auth": {"token": "${GATEWAY_TOKEN}"}}}
+  - path: nginx.conf
+    mount: /etc/nginx/nginx.conf
+    read_only: true
+    services: [openclaw_proxy]
+    content: "listen ${PORT};"
+`
+
+func TestParseRecipe_Files(t *testing.T) {
+	r, err := Parse([]byte(testFilesYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if len(r.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(r.Files))
+	}
+}
+
+func TestRenderFiles(t *testing.T) {
+	r, _ := Parse([]byte(testFilesYAML))
+	files, err := RenderFiles(r, map[string]string{"GATEWAY_TOKEN": "tok123", "PORT": "8080"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files[0].Path != "config/openclaw.json" || files[0].Mode != 0600 || files[0].Owner != "1000:1000" {
+		t.Fatalf("unexpected file: %+v", files[0])
+	}
+	if !strings.Contains(string(files[0].Content), `"token": "tok123"`) {
+		t.Fatalf("expected token to be expanded, got %s", files[0].Content)
+	}
+	if files[1].Mode != 0644 {
+		t.Fatalf("expected default mode 0644, got %o", files[1].Mode)
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	r := &Recipe{
+		Prompts:     []Prompt{{Key: "DOMAIN"}, {Key: "GATEWAY_TOKEN", Secret: true}},
+		Environment: map[string]string{"DB_PASSWORD": "auto_generate_32", "PORT": "8080"},
+	}
+	keys := r.SecretKeys()
+	if len(keys) != 2 || keys[0] != "DB_PASSWORD" || keys[1] != "GATEWAY_TOKEN" {
+		t.Fatalf("unexpected secret keys: %v", keys)
+	}
+
+	content := []byte(`{"token": "tok123", "db": "pw", "port": 8080}`)
+	got := string(MaskSecrets(content, []string{"tok123", "", "pw"}))
+	if got != `{"token": "********", "db": "********", "port": 8080}` {
+		t.Fatalf("unexpected masked content: %s", got)
+	}
+}
+
+func TestGenerateCompose_FileMounts(t *testing.T) {
+	r, _ := Parse([]byte(testFilesYAML))
+	out, err := GenerateCompose(r, nil, 18789)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := string(out)
+	if !strings.Contains(s, "./config/openclaw.json:/home/node/.openclaw/openclaw.json") {
+		t.Fatalf("expected primary bind mount, got:\n%s", s)
+	}
+	if !strings.Contains(s, "./nginx.conf:/etc/nginx/nginx.conf:ro") {
+		t.Fatalf("expected read-only sidecar bind mount, got:\n%s", s)
+	}
+	if strings.Contains(s, "./config/openclaw.json: null") {
+		t.Fatal("file mount should not be declared as a named volume")
+	}
+}
+
+func TestValidate_Files(t *testing.T) {
+	base := func(f File) *Recipe {
+		return &Recipe{Name: "app", Version: "1", Image: "app", Ports: []int{80}, Files: []File{f}}
+	}
+	bad := []File{
+		{Path: "/etc/passwd"},
+		{Path: "../escape.conf"},
+		{Path: ".env"},
+		{Path: "app.conf", Mode: "999"},
+		{Path: "app.conf", Mount: "relative/path"},
+		{Path: "app.conf", Services: []string{"missing"}},
+	}
+	for _, f := range bad {
+		if err := base(f).Validate(); err == nil {
+			t.Fatalf("expected %+v to be rejected", f)
+		}
+	}
+
+	r := base(File{Path: "app.json", Mount: "/data/app.json"})
+	r.Volumes = []string{"app_data:/data"}
+	if err := r.Validate(); err == nil {
+		t.Fatal("expected a file mounted inside a volume to be rejected")
+	}
+	r.Files[0].Mount = "/etc/app/app.json"
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// openclaw's own config lives in its volume and is rewritten by the app, so
+// bunkr's settings are merged into it at install and again on every update.
+func TestShippedOpenclaw_MergesConfig(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "recipes", "openclaw.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.InitCommand != "setup" {
+		t.Fatalf("expected setup to create the app's config, got %q", r.InitCommand)
+	}
+	if len(r.Files) != 1 || !r.Files[0].ReadOnly || volumeAt(r.Volumes, r.Files[0].Mount) != "" {
+		t.Fatalf("expected one read-only settings file outside the volume, got %+v", r.Files)
+	}
+
+	merge := func(commands []string) bool {
+		return slices.ContainsFunc(commands, func(c string) bool {
+			return strings.Contains(c, r.Files[0].Mount) && strings.Contains(c, "/home/node/.openclaw/openclaw.json")
+		})
+	}
+	if !merge(r.PostInit) {
+		t.Fatalf("expected post_init to merge the settings on install, got %v", r.PostInit)
+	}
+	_, post := r.UpdateHooks(r.Version, r.Version)
+	if len(post) != 1 || !merge(post[0].Run) {
+		t.Fatalf("expected a post-update hook to merge the settings, got %+v", post)
+	}
+}
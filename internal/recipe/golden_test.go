@@ -0,0 +1,159 @@
+package recipe
+
+import (
+	"bytes"
+	"flag"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files under testdata/golden")
+
+// TestGolden renders every shipped recipe the way install does and compares
+// the output with testdata/golden/<recipe>/. After an intended change, run:
+//
+//	go test ./internal/recipe -run TestGolden -update
+func TestGolden(t *testing.T) {
+	recipesDir := filepath.Join("..", "..", "recipes")
+	current, _ := filepath.Glob(filepath.Join(recipesDir, "*.yaml"))
+	pinned, _ := filepath.Glob(filepath.Join(recipesDir, "*", "*.yaml"))
+
+	seen := make(map[string]bool)
+	for _, path := range append(current, pinned...) {
+		if filepath.Base(path) == "index.yaml" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := Parse(data)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if err := r.Validate(); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+
+		name := r.Name
+		if filepath.Dir(path) != recipesDir {
+			name += "@" + r.Version
+		}
+		seen[name] = true
+
+		t.Run(name, func(t *testing.T) {
+			compose, env := renderForGolden(t, r)
+			dir := filepath.Join("testdata", "golden", name)
+			checkGolden(t, filepath.Join(dir, "docker-compose.yml"), compose)
+			checkGolden(t, filepath.Join(dir, "env"), env)
+		})
+	}
+
+	// Golden files for recipes that no longer exist
+	dirs, _ := os.ReadDir(filepath.Join("testdata", "golden"))
+	for _, d := range dirs {
+		if !seen[d.Name()] {
+			if *update {
+				os.RemoveAll(filepath.Join("testdata", "golden", d.Name()))
+				continue
+			}
+			t.Errorf("testdata/golden/%s has no matching recipe, run with -update to remove it", d.Name())
+		}
+	}
+}
+
+// renderForGolden mirrors install's plan phase with fixed prompt answers,
+// secrets from a seeded source and each endpoint on its preferred host port.
+func renderForGolden(t *testing.T, r *Recipe) (compose, env []byte) {
+	t.Helper()
+
+	orig := randRead
+	randRead = mathrand.New(mathrand.NewSource(1)).Read
+	defer func() { randRead = orig }()
+
+	values := make(map[string]string)
+	for _, p := range r.Prompts {
+		values[p.Key] = fakePromptValue(r.Name, p)
+	}
+	r.Environment = ExpandAutoGenerate(r.Environment)
+	merged := MergeValues(values, r.Environment)
+
+	var ports []int
+	for _, ep := range r.EndpointList() {
+		ports = append(ports, ep.DesiredHostPort())
+	}
+	compose, err := GenerateCompose(r, merged, ports...)
+	if err != nil {
+		t.Fatalf("GenerateCompose: %v", err)
+	}
+	return compose, GenerateEnv(merged)
+}
+
+func fakePromptValue(app string, p Prompt) string {
+	key := strings.ToUpper(p.Key)
+	switch {
+	case p.Default != "":
+		return p.Default
+	case len(p.Options) > 0:
+		return p.Options[0]
+	case key == "DOMAIN":
+		return app + ".example.com"
+	case strings.HasSuffix(key, "_DOMAIN"):
+		return strings.ToLower(strings.TrimSuffix(key, "_DOMAIN")) + "." + app + ".example.com"
+	case strings.Contains(key, "EMAIL"):
+		return "admin@example.com"
+	case p.Secret:
+		return "test-" + strings.ToLower(p.Key) + "-secret"
+	}
+	return "test-" + strings.ToLower(p.Key)
+}
+
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the rendered output (run with -update if the change is intended):\n%s", path, lineDiff(string(want), string(got)))
+	}
+}
+
+// lineDiff lists lines only in want (-) or only in got (+), in order.
+func lineDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	inWant := make(map[string]int)
+	for _, l := range wantLines {
+		inWant[l]++
+	}
+	inGot := make(map[string]int)
+	for _, l := range gotLines {
+		inGot[l]++
+	}
+
+	var b strings.Builder
+	for _, l := range wantLines {
+		if inGot[l] == 0 {
+			b.WriteString("- " + l + "\n")
+		}
+	}
+	for _, l := range gotLines {
+		if inWant[l] == 0 {
+			b.WriteString("+ " + l + "\n")
+		}
+	}
+	return b.String()
+}
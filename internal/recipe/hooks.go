@@ -0,0 +1,128 @@
+package recipe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hook runs shell commands in a one-off container of a service (the primary
+// when Service is empty). The app directory is mounted at /bunkr so hooks
+// can leave backups next to the compose file.
+type Hook struct {
+	Name    string   `yaml:"name"`
+	Service string   `yaml:"service,omitempty"`
+	Run     []string `yaml:"run,omitempty"`
+}
+
+type Hooks struct {
+	PreUpdate  []Hook `yaml:"pre_update,omitempty"`
+	PostUpdate []Hook `yaml:"post_update,omitempty"`
+}
+
+// Migration holds hooks that only run when updating from a version matching
+// From to a version matching To, e.g. from: "<2.13.0", to: ">=2.13.0".
+type Migration struct {
+	Name  string `yaml:"name"`
+	From  string `yaml:"from,omitempty"`
+	To    string `yaml:"to,omitempty"`
+	Hooks `yaml:",inline"`
+}
+
+// UpdateHooks returns the hooks to run around an update from one version to
+// another: recipe-wide pre_update hooks, then matching migrations, and the
+// reverse order after the update.
+func (r *Recipe) UpdateHooks(from, to string) (pre, post []Hook) {
+	pre = append(pre, r.Hooks.PreUpdate...)
+	var migrationPost []Hook
+	for _, m := range r.Migrations {
+		if MatchVersion(from, m.From) && MatchVersion(to, m.To) {
+			pre = append(pre, m.PreUpdate...)
+			migrationPost = append(migrationPost, m.PostUpdate...)
+		}
+	}
+	post = append(migrationPost, r.Hooks.PostUpdate...)
+	return pre, post
+}
+
+// MatchVersion reports whether version satisfies every space-separated
+// comparison in constraint (=, !=, <, <=, >, >=). An empty constraint
+// matches any version.
+func MatchVersion(version, constraint string) bool {
+	for _, c := range strings.Fields(constraint) {
+		op, want := splitConstraint(c)
+		cmp := CompareVersions(version, want)
+		var ok bool
+		switch op {
+		case "=", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func splitConstraint(c string) (op, version string) {
+	for _, op := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+		if strings.HasPrefix(c, op) {
+			return op, strings.TrimPrefix(c, op)
+		}
+	}
+	return "=", c
+}
+
+func validateConstraint(c string) error {
+	for _, part := range strings.Fields(c) {
+		if _, v := splitConstraint(part); v == "" {
+			return fmt.Errorf("invalid version constraint %q", c)
+		}
+	}
+	return nil
+}
+
+func (r *Recipe) validateHooks(services map[string]bool) error {
+	check := func(hooks []Hook) error {
+		for _, h := range hooks {
+			if len(h.Run) == 0 {
+				return fmt.Errorf("hook %s has no commands", h.Name)
+			}
+			if h.Service != "" && !services[h.Service] {
+				return fmt.Errorf("hook %s runs in unknown service %s", h.Name, h.Service)
+			}
+		}
+		return nil
+	}
+
+	if err := check(r.Hooks.PreUpdate); err != nil {
+		return err
+	}
+	if err := check(r.Hooks.PostUpdate); err != nil {
+		return err
+	}
+	for _, m := range r.Migrations {
+		if err := validateConstraint(m.From); err != nil {
+			return fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+		if err := validateConstraint(m.To); err != nil {
+			return fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+		if err := check(m.PreUpdate); err != nil {
+			return fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+		if err := check(m.PostUpdate); err != nil {
+			return fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package recipe
+
+import "testing"
+
+const testHooksYAML = `
+name: n8n
+version: "2.13.0"
+image: n8n:2.13.0
+ports:
+  - 5678
+services:
+  - name: n8n_db
+    image: postgres:16-alpine
+hooks:
+  pre_update:
+    - name: backup
+      service: n8n_db
+      run:
+        - pg_dump -h n8n_db -U n8n n8n > /bunkr/backup.sql
+  post_update:
+    - name: verify
+      run:
+        - n8n --version
+migrations:
+  - name: postgres-16
+    from: "<2.13.0"
+    to: ">=2.13.0"
+    pre_update:
+      - name: dump-for-upgrade
+        service: n8n_db
+        run:
+          - pg_dumpall -h n8n_db -U n8n > /bunkr/upgrade.sql
+    post_update:
+      - name: restore
+        service: n8n_db
+        run:
+          - psql -h n8n_db -U n8n < /bunkr/upgrade.sql
+`
+
+func TestUpdateHooks(t *testing.T) {
+	r, err := Parse([]byte(testHooksYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	pre, post := r.UpdateHooks("2.12.2", "2.13.0")
+	if len(pre) != 2 || pre[0].Name != "backup" || pre[1].Name != "dump-for-upgrade" {
+		t.Fatalf("unexpected pre hooks: %+v", pre)
+	}
+	if len(post) != 2 || post[0].Name != "restore" || post[1].Name != "verify" {
+		t.Fatalf("unexpected post hooks: %+v", post)
+	}
+
+	pre, post = r.UpdateHooks("2.13.0", "2.14.0")
+	if len(pre) != 1 || len(post) != 1 {
+		t.Fatalf("expected only recipe-wide hooks outside the migration range, got %d pre, %d post", len(pre), len(post))
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	cases := []struct {
+		version, constraint string
+		want                bool
+	}{
+		{"2.12.2", "", true},
+		{"2.12.2", "<2.13.0", true},
+		{"2.13.0", "<2.13.0", false},
+		{"2.13.0", ">=2.13.0", true},
+		{"2.5.0", ">=2.0.0 <3.0.0", true},
+		{"3.0.0", ">=2.0.0 <3.0.0", false},
+		{"1.0.0", "1.0.0", true},
+		{"1.0.1", "!=1.0.0", true},
+	}
+	for _, tc := range cases {
+		if got := MatchVersion(tc.version, tc.constraint); got != tc.want {
+			t.Fatalf("MatchVersion(%q, %q) = %v, want %v", tc.version, tc.constraint, got, tc.want)
+		}
+	}
+}
+
+func TestValidate_Hooks(t *testing.T) {
+	base := func() *Recipe {
+		return &Recipe{Name: "app", Version: "1", Image: "app", Ports: []int{80}}
+	}
+
+	r := base()
+	r.Hooks.PreUpdate = []Hook{{Name: "empty"}}
+	if err := r.Validate(); err == nil {
+		t.Fatal("expected error for hook without commands")
+	}
+
+	r = base()
+	r.Hooks.PostUpdate = []Hook{{Name: "x", Service: "missing", Run: []string{"true"}}}
+	if err := r.Validate(); err == nil {
+		t.Fatal("expected error for hook in unknown service")
+	}
+
+	r = base()
+	r.Migrations = []Migration{{Name: "m", From: ">="}}
+	if err := r.Validate(); err == nil {
+		t.Fatal("expected error for empty version in constraint")
+	}
+}
@@ -0,0 +1,852 @@
+package recipe
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ImportOptions control how a compose file is turned into a recipe.
+type ImportOptions struct {
+	Name    string // recipe name; defaults to the primary service's name
+	Primary string // primary service; picked heuristically when empty
+	Source  string // compose file name, used in the description
+}
+
+// backingImages are images that back an app rather than being the app, used
+// to pick the primary service.
+var backingImages = []string{
+	"postgres", "postgis", "mysql", "mariadb", "redis", "valkey", "keydb", "mongo",
+	"clickhouse", "memcached", "elasticsearch", "opensearch", "rabbitmq", "nats",
+	"etcd", "zookeeper", "kafka",
+}
+
+var (
+	varRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)((?::?[-?+])[^}]*)?\}`)
+	namePattern   = regexp.MustCompile(`[^a-z0-9_-]+`)
+)
+
+// importedService is a compose service converted to the recipe schema, plus
+// the parts only the primary service can use.
+type importedService struct {
+	Service
+	ports []int
+}
+
+type importer struct {
+	r       *Recipe
+	issues  []LintIssue
+	secrets map[string]string // literal secret value -> variable holding it
+	prompts map[string]bool
+}
+
+// Import converts a docker-compose file into a recipe. Compose features bunkr
+// can't express are reported as warnings rather than dropped silently, and
+// every heuristic rewrite is reported as info.
+func Import(data []byte, opts ImportOptions) (*Recipe, []LintIssue, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	rawServices, ok := doc["services"].(map[string]interface{})
+	if !ok || len(rawServices) == 0 {
+		return nil, nil, fmt.Errorf("compose file has no services")
+	}
+
+	im := &importer{
+		r:       &Recipe{},
+		secrets: make(map[string]string),
+		prompts: make(map[string]bool),
+	}
+	im.checkTopLevel(doc)
+
+	primary := opts.Primary
+	if primary == "" {
+		var err error
+		if primary, err = pickPrimary(rawServices); err != nil {
+			return nil, nil, err
+		}
+	} else if _, ok := rawServices[primary]; !ok {
+		return nil, nil, fmt.Errorf("service %s not found in compose file", primary)
+	}
+
+	r := im.r
+	r.Name = opts.Name
+	if r.Name == "" {
+		r.Name = primary
+	}
+	r.Name = namePattern.ReplaceAllString(strings.ToLower(r.Name), "-")
+	if r.Name != primary {
+		im.info(r.Name, "primary service %s is renamed to %s; update anything that connects to it by name", primary, r.Name)
+	}
+	r.Description = "Imported from docker-compose.yml"
+	if opts.Source != "" {
+		r.Description = "Imported from " + path.Base(opts.Source)
+	}
+
+	var names []string
+	for name := range rawServices {
+		if name != primary {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == r.Name {
+			return nil, nil, fmt.Errorf("service %s clashes with the recipe name, choose another with --name", name)
+		}
+	}
+
+	// Primary first so shared secrets are named after its variables
+	conditions := make(map[string]string)
+	im.convertPrimary(primary, asMap(rawServices[primary]), conditions)
+	for _, name := range names {
+		svc := im.convertService(name, asMap(rawServices[name]), primary, conditions)
+		r.Services = append(r.Services, svc.Service)
+	}
+	for i := range r.Services {
+		if c, ok := conditions[r.Services[i].Name]; ok {
+			r.Services[i].Condition = c
+		}
+	}
+
+	r.Prompts = append([]Prompt{{Key: "DOMAIN", Label: "Domain for " + r.Name, Required: true}}, r.Prompts...)
+	im.info(r.Name, "containers get bunkr's defaults (cap_drop: [ALL], no-new-privileges); run bunkr recipe lint to check the cap_add each image needs")
+
+	if err := r.Validate(); err != nil {
+		im.warn(r.Name, "recipe needs manual changes before it can be installed: %v", err)
+	}
+	return r, im.issues, nil
+}
+
+// MarshalRecipe renders r as recipe YAML, leaving out empty fields.
+func MarshalRecipe(r *Recipe) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(r); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (im *importer) warn(service, format string, args ...interface{}) {
+	im.issues = append(im.issues, LintIssue{Level: LintWarn, Service: service, Message: fmt.Sprintf(format, args...)})
+}
+
+func (im *importer) info(service, format string, args ...interface{}) {
+	im.issues = append(im.issues, LintIssue{Level: LintInfo, Service: service, Message: fmt.Sprintf(format, args...)})
+}
+
+func (im *importer) checkTopLevel(doc map[string]interface{}) {
+	var keys []string
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch {
+		case k == "services", k == "version", k == "name", strings.HasPrefix(k, "x-"):
+		case k == "volumes":
+			for name, v := range asMap(doc[k]) {
+				if len(asMap(v)) > 0 {
+					im.warn("compose", "volume %s: driver and external settings are not supported, a local volume is used", name)
+				}
+			}
+		case k == "networks":
+			im.warn("compose", "custom networks are not supported; bunkr puts every app on its own network with internal-only backing services")
+		default:
+			im.warn("compose", "top-level %s is not supported and was not imported", k)
+		}
+	}
+}
+
+// pickPrimary prefers the one non-backing service that publishes ports, then
+// the non-backing service that depends on the most others.
+func pickPrimary(services map[string]interface{}) (string, error) {
+	var names []string
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var apps, published []string
+	for _, name := range names {
+		svc := asMap(services[name])
+		if isBackingImage(asString(svc["image"])) {
+			continue
+		}
+		apps = append(apps, name)
+		if len(asList(svc["ports"])) > 0 {
+			published = append(published, name)
+		}
+	}
+	if len(published) == 1 {
+		return published[0], nil
+	}
+	candidates := published
+	if len(candidates) == 0 {
+		candidates = apps
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("could not tell which service is the app, choose one with --primary")
+	}
+
+	best, bestDeps := "", -1
+	for _, name := range candidates {
+		deps := len(dependsOn(asMap(services[name])["depends_on"]))
+		if deps > bestDeps {
+			best, bestDeps = name, deps
+		}
+	}
+	return best, nil
+}
+
+func isBackingImage(image string) bool {
+	base, _, _ := strings.Cut(path.Base(image), ":")
+	for _, b := range backingImages {
+		if strings.Contains(base, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// handledKeys are compose service keys convertService understands.
+var handledKeys = map[string]bool{
+	"image": true, "command": true, "entrypoint": true, "environment": true, "ports": true,
+	"volumes": true, "healthcheck": true, "ulimits": true, "tmpfs": true, "user": true,
+	"working_dir": true, "labels": true, "extra_hosts": true, "depends_on": true,
+	"cap_add": true, "cap_drop": true, "read_only": true, "security_opt": true,
+	"restart": true, "logging": true, "mem_limit": true, "cpus": true, "pids_limit": true,
+	"deploy": true, "expose": true, "container_name": true, "hostname": true, "networks": true,
+}
+
+func (im *importer) convertService(name string, raw map[string]interface{}, primary string, conditions map[string]string) importedService {
+	var svc importedService
+	svc.Name = name
+
+	var keys []string
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !handledKeys[k] {
+			im.warn(name, "%s is not supported and was not imported", k)
+		}
+	}
+
+	svc.Image = asString(raw["image"])
+	if svc.Image == "" {
+		im.warn(name, "no image; bunkr can't build images, push one to a registry and set image")
+	}
+	svc.Command = joinCommand(raw["command"])
+	svc.Entrypoint = joinCommand(raw["entrypoint"])
+	svc.User = asString(raw["user"])
+	svc.WorkingDir = asString(raw["working_dir"])
+	svc.Labels = asStringMap(raw["labels"])
+	svc.ExtraHosts = asExtraHosts(raw["extra_hosts"])
+	svc.Tmpfs = asStringList(raw["tmpfs"])
+	svc.Environment = im.environment(name, raw["environment"])
+	svc.Volumes = im.volumes(name, raw["volumes"], &svc.Tmpfs)
+	svc.ports = im.ports(name, raw["ports"])
+	svc.HealthCheck = healthCheck(raw["healthcheck"])
+	svc.Ulimits = im.ulimits(name, raw["ulimits"])
+	svc.Security = im.security(name, raw)
+	svc.Resources = im.resources(name, raw)
+	svc.Restart = asString(raw["restart"])
+	if err := ValidateRestart(svc.Restart); err != nil {
+		im.warn(name, "%v, using the default", err)
+		svc.Restart = ""
+	}
+	svc.Logging = im.logging(name, raw["logging"])
+
+	for dep, condition := range dependsOn(raw["depends_on"]) {
+		if dep == primary {
+			im.warn(name, "depends on the primary service, which bunkr always starts last; dependency dropped")
+			continue
+		}
+		svc.DependsOn = append(svc.DependsOn, dep)
+		if condition != "" && condition != ConditionStarted {
+			conditions[dep] = condition
+		}
+	}
+	sort.Strings(svc.DependsOn)
+
+	if name != primary {
+		if len(svc.ports) > 0 {
+			im.warn(name, "ports are not published; only the primary service is exposed")
+		}
+		if svc.Image != "" && !isBackingImage(svc.Image) {
+			svc.Egress = true
+			im.info(name, "keeps outbound network access (egress: true); remove it if the service doesn't need the internet")
+		}
+	}
+	if raw["container_name"] != nil || raw["hostname"] != nil {
+		im.info(name, "container_name and hostname are ignored; other services reach it as %s", name)
+	}
+	if raw["networks"] != nil {
+		im.info(name, "networks are ignored; bunkr assigns them")
+	}
+	return svc
+}
+
+// convertPrimary fills the recipe's own fields from the primary service and
+// flags what only sidecars support.
+func (im *importer) convertPrimary(name string, raw map[string]interface{}, conditions map[string]string) {
+	svc := im.convertService(name, raw, name, conditions)
+	if len(svc.ports) == 0 {
+		svc.ports = im.ports(name, raw["expose"])
+	}
+
+	r := im.r
+	r.Image = svc.Image
+	r.Version = ImageVersion(svc.Image)
+	r.Command = svc.Command
+	r.User = svc.User
+	r.Tmpfs = svc.Tmpfs
+	r.Volumes = svc.Volumes
+
+	// Generated variables were collected while converting the environment
+	generated := r.Environment
+	r.Environment = svc.Environment
+	for k, v := range generated {
+		if r.Environment == nil {
+			r.Environment = make(map[string]string)
+		}
+		if _, ok := r.Environment[k]; !ok {
+			r.Environment[k] = v
+		}
+	}
+	r.Security = svc.Security
+	r.Resources = svc.Resources
+	r.Restart = svc.Restart
+	r.Logging = svc.Logging
+
+	unsupported := map[string]bool{
+		"entrypoint":  svc.Entrypoint != "",
+		"working_dir": svc.WorkingDir != "",
+		"labels":      len(svc.Labels) > 0,
+		"extra_hosts": len(svc.ExtraHosts) > 0,
+		"healthcheck": svc.HealthCheck != nil,
+		"ulimits":     len(svc.Ulimits) > 0,
+	}
+	for _, k := range []string{"entrypoint", "working_dir", "labels", "extra_hosts", "healthcheck", "ulimits"} {
+		if unsupported[k] {
+			im.warn(r.Name, "%s is not supported on the primary service and was not imported", k)
+		}
+	}
+	if svc.HealthCheck != nil {
+		im.info(r.Name, "add a health_check url for bunkr to poll after install")
+	}
+
+	switch len(svc.ports) {
+	case 0:
+		im.warn(r.Name, "publishes no ports; add the port the app listens on")
+	case 1:
+		r.Ports = svc.ports
+	default:
+		r.Endpoints = append(r.Endpoints, Endpoint{Name: "web", Port: svc.ports[0], DomainPrompt: "DOMAIN"})
+		for _, p := range svc.ports[1:] {
+			r.Endpoints = append(r.Endpoints, Endpoint{Name: fmt.Sprintf("port-%d", p), Port: p, Protocol: ProtocolTCP})
+			im.warn(r.Name, "port %d is imported as a public tcp endpoint; make it an http endpoint with a path or domain_prompt if it serves HTTP", p)
+		}
+	}
+}
+
+// environment converts a service's environment, turning literal secrets into
+// generated values and public hostnames into ${DOMAIN}.
+func (im *importer) environment(service string, raw interface{}) map[string]string {
+	env := asStringMap(raw)
+	for _, item := range asList(raw) {
+		// A bare KEY passes the host's value through
+		if s := asString(item); s != "" && !strings.Contains(s, "=") {
+			if env == nil {
+				env = make(map[string]string)
+			}
+			env[s] = "${" + s + "}"
+		}
+	}
+	if len(env) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := env[k]
+		switch {
+		case strings.Contains(v, "${"):
+			env[k] = im.references(service, v)
+		case isSecretKey(k) && v != "" && !isPlainValue(v):
+			env[k] = im.secret(service, k, v)
+		case isDomainKey(k):
+			if replaced, ok := replaceHost(v); ok {
+				env[k] = replaced
+				im.info(service, "%s now uses ${DOMAIN}", k)
+			}
+		}
+	}
+	return env
+}
+
+// references resolves ${VAR} references, which compose reads from the
+// host environment and bunkr from the generated .env file.
+func (im *importer) references(service, v string) string {
+	return varRefPattern.ReplaceAllStringFunc(v, func(ref string) string {
+		m := varRefPattern.FindStringSubmatch(ref)
+		name, modifier := m[1], m[2]
+		switch {
+		case name == "DOMAIN" || im.r.Environment[name] != "" || im.prompts[name]:
+		case isDomainKey(name):
+			im.info(service, "${%s} replaced with ${DOMAIN}", name)
+			return "${DOMAIN}"
+		case isSecretKey(name) && isGeneratable(name):
+			if im.r.Environment == nil {
+				im.r.Environment = make(map[string]string)
+			}
+			im.r.Environment[name] = autoGenerateFor(name)
+			im.info(service, "%s is generated on install (%s)", name, autoGenerateFor(name))
+		case strings.HasPrefix(modifier, ":-") || strings.HasPrefix(modifier, "-"):
+		default:
+			im.addPrompt(service, name, isSecretKey(name))
+		}
+		return ref
+	})
+}
+
+// secret replaces a literal secret with a generated variable, shared by
+// every container that used the same literal.
+func (im *importer) secret(service, key, value string) string {
+	if name, ok := im.secrets[value]; ok {
+		return "${" + name + "}"
+	}
+	if im.r.Environment == nil {
+		im.r.Environment = make(map[string]string)
+	}
+
+	name := key
+	if service != im.r.Name {
+		name = strings.ToUpper(strings.ReplaceAll(service, "-", "_")) + "_" + key
+	}
+	im.secrets[value] = name
+	if !isGeneratable(key) {
+		im.addPrompt(service, name, true)
+		return "${" + name + "}"
+	}
+	im.r.Environment[name] = autoGenerateFor(key)
+	im.info(service, "%s is generated on install (%s)", key, autoGenerateFor(key))
+	if service == im.r.Name {
+		return autoGenerateFor(key)
+	}
+	return "${" + name + "}"
+}
+
+func (im *importer) addPrompt(service, key string, secret bool) {
+	if im.prompts[key] {
+		return
+	}
+	im.prompts[key] = true
+	im.r.Prompts = append(im.r.Prompts, Prompt{Key: key, Label: key, Required: true, Secret: secret})
+	im.info(service, "added a prompt for %s", key)
+}
+
+// isGeneratable reports secrets the app defines for itself, as opposed to
+// credentials issued by someone else (API keys, access tokens).
+func isGeneratable(key string) bool {
+	k := strings.ToUpper(key)
+	for _, word := range []string{"TOKEN", "API_KEY", "APIKEY", "ACCESS_KEY"} {
+		if strings.Contains(k, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func autoGenerateFor(key string) string {
+	k := strings.ToUpper(key)
+	if strings.Contains(k, "KEY") || strings.Contains(k, "SECRET") {
+		return "auto_generate_64"
+	}
+	return "auto_generate_32"
+}
+
+func isSecretKey(key string) bool {
+	k := strings.ToUpper(key)
+	for _, suffix := range []string{"_FILE", "_PATH", "_LENGTH", "_USER", "_USERNAME"} {
+		if strings.HasSuffix(k, suffix) {
+			return false
+		}
+	}
+	for _, word := range []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "SALT"} {
+		if strings.Contains(k, word) {
+			return true
+		}
+	}
+	return strings.HasSuffix(k, "_KEY") || strings.HasSuffix(k, "_PASS") || k == "KEY"
+}
+
+// isPlainValue reports values that are settings rather than secrets.
+func isPlainValue(v string) bool {
+	switch strings.ToLower(v) {
+	case "true", "false", "yes", "no", "on", "off":
+		return true
+	}
+	_, err := strconv.ParseFloat(v, 64)
+	return err == nil
+}
+
+func isDomainKey(key string) bool {
+	k := strings.ToUpper(key)
+	for _, backing := range []string{"DB", "DATABASE", "POSTGRES", "MYSQL", "REDIS", "SMTP", "MAIL", "MONGO", "CLICKHOUSE", "AMQP", "RABBIT", "S3", "ELASTIC"} {
+		if strings.Contains(k, backing) {
+			return false
+		}
+	}
+	return strings.Contains(k, "DOMAIN") || strings.Contains(k, "URL") ||
+		k == "HOST" || k == "HOSTNAME" || k == "VIRTUAL_HOST" ||
+		strings.HasSuffix(k, "_HOST") || strings.HasSuffix(k, "_HOSTNAME")
+}
+
+// replaceHost swaps a public hostname in v, bare or inside a URL, for
+// ${DOMAIN}. Service names, localhost and IPs are left alone.
+func replaceHost(v string) (string, bool) {
+	host := v
+	if u, err := url.Parse(v); err == nil && u.Scheme != "" && u.Host != "" {
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(v); err == nil {
+		host = h
+	}
+	if !strings.Contains(host, ".") || net.ParseIP(host) != nil || strings.Contains(host, "/") {
+		return v, false
+	}
+	return strings.Replace(v, host, "${DOMAIN}", 1), true
+}
+
+// ports returns the container ports a service publishes.
+func (im *importer) ports(service string, raw interface{}) []int {
+	var ports []int
+	for _, item := range asList(raw) {
+		var spec, protocol string
+		if m := asMap(item); m != nil {
+			spec, protocol = asString(m["target"]), asString(m["protocol"])
+		} else {
+			spec = asString(item)
+			spec, protocol, _ = strings.Cut(spec, "/")
+		}
+		if protocol != "" && protocol != "tcp" {
+			im.warn(service, "port %s/%s is not supported, only tcp", spec, protocol)
+			continue
+		}
+		parts := strings.Split(spec, ":")
+		target := parts[len(parts)-1]
+		p, err := strconv.Atoi(target)
+		if err != nil {
+			im.warn(service, "port %s is not supported (ranges and variables can't be imported)", spec)
+			continue
+		}
+		ports = append(ports, p)
+	}
+	return ports
+}
+
+func (im *importer) volumes(service string, raw interface{}, tmpfs *[]string) []string {
+	var volumes []string
+	for _, item := range asList(raw) {
+		if m := asMap(item); m != nil {
+			source, target := asString(m["source"]), asString(m["target"])
+			switch asString(m["type"]) {
+			case "tmpfs":
+				*tmpfs = append(*tmpfs, target)
+				continue
+			case "volume", "bind":
+			default:
+				im.warn(service, "volume of type %s is not supported", asString(m["type"]))
+				continue
+			}
+			v := source + ":" + target
+			if source == "" {
+				v = target
+			}
+			if ro, _ := m["read_only"].(bool); ro {
+				v += ":ro"
+			}
+			item = v
+		}
+
+		v := asString(item)
+		source, _, hasSource := strings.Cut(v, ":")
+		if !hasSource {
+			// Anonymous volumes are recreated on every "compose down"
+			named := strings.Trim(strings.ReplaceAll(service+"_"+path.Base(source), ".", "_"), "_")
+			im.info(service, "anonymous volume %s is now the named volume %s", source, named)
+			v = named + ":" + source
+		} else if strings.HasPrefix(source, "/") || strings.HasPrefix(source, "~") {
+			im.warn(service, "bind mounts host path %s; make sure it exists on the server", source)
+		} else if strings.HasPrefix(source, ".") {
+			im.warn(service, "bind mounts %s from the app directory; add it under files so bunkr writes it", source)
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes
+}
+
+func healthCheck(raw interface{}) *ContainerHealthCheck {
+	m := asMap(raw)
+	if m == nil {
+		return nil
+	}
+	if disabled, _ := m["disable"].(bool); disabled {
+		return nil
+	}
+	hc := &ContainerHealthCheck{
+		Interval:    asString(m["interval"]),
+		Timeout:     asString(m["timeout"]),
+		StartPeriod: asString(m["start_period"]),
+	}
+	if n, ok := m["retries"].(int); ok {
+		hc.Retries = n
+	}
+	if s := asString(m["test"]); s != "" {
+		hc.Test = []string{"CMD-SHELL", s}
+	} else {
+		hc.Test = asStringList(m["test"])
+	}
+	if len(hc.Test) == 0 {
+		return nil
+	}
+	return hc
+}
+
+func (im *importer) ulimits(service string, raw interface{}) map[string]Ulimit {
+	m := asMap(raw)
+	if len(m) == 0 {
+		return nil
+	}
+	ulimits := make(map[string]Ulimit, len(m))
+	for name, v := range m {
+		switch val := v.(type) {
+		case int:
+			ulimits[name] = Ulimit{Soft: val, Hard: val}
+		case map[string]interface{}:
+			soft, _ := val["soft"].(int)
+			hard, _ := val["hard"].(int)
+			ulimits[name] = Ulimit{Soft: soft, Hard: hard}
+		default:
+			im.warn(service, "ulimit %s could not be read", name)
+		}
+	}
+	return ulimits
+}
+
+func (im *importer) security(service string, raw map[string]interface{}) Security {
+	sec := Security{
+		CapAdd:  asStringList(raw["cap_add"]),
+		CapDrop: asStringList(raw["cap_drop"]),
+	}
+	// The compose file ran with Docker's default capabilities, so grant
+	// the ones its image usually needs once bunkr drops them all
+	if sec.CapAdd == nil && sec.CapDrop == nil {
+		image := asString(raw["image"])
+		sec.CapAdd = SuggestedCaps(image, asString(raw["user"]))
+		if len(sec.CapAdd) > 0 {
+			im.info(service, "cap_add set to %s, which %s usually needs; remove any it doesn't", strings.Join(sec.CapAdd, ", "), image)
+		}
+	}
+	sec.ReadOnly, _ = raw["read_only"].(bool)
+	for _, opt := range asStringList(raw["security_opt"]) {
+		key, value, _ := strings.Cut(strings.Replace(opt, "=", ":", 1), ":")
+		switch key {
+		case "no-new-privileges":
+			if value == "false" {
+				off := false
+				sec.NoNewPrivileges = &off
+			}
+		case "seccomp":
+			sec.Seccomp = value
+		case "apparmor":
+			sec.AppArmor = value
+		default:
+			im.warn(service, "security_opt %s is not supported", opt)
+		}
+	}
+	return sec
+}
+
+func (im *importer) resources(service string, raw map[string]interface{}) Resources {
+	var res Resources
+	res.Memory = asString(raw["mem_limit"])
+	res.CPUs = asFloat(raw["cpus"])
+	res.Pids, _ = raw["pids_limit"].(int)
+
+	deploy := asMap(raw["deploy"])
+	for k := range deploy {
+		if k != "resources" {
+			im.warn(service, "deploy.%s is not supported (bunkr runs one container per service)", k)
+		}
+	}
+	limits := asMap(asMap(deploy["resources"])["limits"])
+	if m := asString(limits["memory"]); m != "" {
+		res.Memory = m
+	}
+	if c := asFloat(limits["cpus"]); c != 0 {
+		res.CPUs = c
+	}
+	if p, ok := limits["pids"].(int); ok {
+		res.Pids = p
+	}
+	if err := res.Validate(); err != nil {
+		im.warn(service, "%v, limits not imported", err)
+		return Resources{}
+	}
+	return res
+}
+
+func (im *importer) logging(service string, raw interface{}) Logging {
+	m := asMap(raw)
+	if m == nil {
+		return Logging{}
+	}
+	opts := asStringMap(m["options"])
+	l := Logging{Driver: asString(m["driver"]), MaxSize: opts["max-size"]}
+	if n, err := strconv.Atoi(opts["max-file"]); err == nil {
+		l.MaxFile = n
+	}
+	if err := l.Validate(); err != nil {
+		im.warn(service, "%v, using the default logging", err)
+		return Logging{}
+	}
+	return l
+}
+
+// ImageVersion returns the recipe version an image reference runs: its tag
+// without a leading "v" (recipes version plausible's v2.1.4 as 2.1.4), or
+// "latest" when it has none. Registry ports and digests are not mistaken
+// for tags.
+func ImageVersion(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	_, tag, ok := strings.Cut(imageTag(image), ":")
+	if !ok || tag == "" {
+		return "latest"
+	}
+	if len(tag) > 1 && tag[0] == 'v' && tag[1] >= '0' && tag[1] <= '9' {
+		return tag[1:]
+	}
+	return tag
+}
+
+// dependsOn reads depends_on in list or map form into service -> condition.
+func dependsOn(raw interface{}) map[string]string {
+	deps := make(map[string]string)
+	for _, item := range asList(raw) {
+		deps[asString(item)] = ""
+	}
+	for name, v := range asMap(raw) {
+		deps[name] = asString(asMap(v)["condition"])
+	}
+	return deps
+}
+
+// joinCommand turns a command in string or list form into a single string.
+func joinCommand(raw interface{}) string {
+	if s := asString(raw); s != "" {
+		return s
+	}
+	var parts []string
+	for _, item := range asList(raw) {
+		s := asString(item)
+		if s == "" || strings.ContainsAny(s, " \t\"'$") {
+			s = strconv.Quote(s)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+func asExtraHosts(raw interface{}) []string {
+	if hosts := asStringList(raw); len(hosts) > 0 {
+		return hosts
+	}
+	var hosts []string
+	for host, ip := range asStringMap(raw) {
+		hosts = append(hosts, host+":"+ip)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func asList(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func asString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case int, float64, bool:
+		return fmt.Sprint(val)
+	}
+	return ""
+}
+
+func asFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case int:
+		return float64(val)
+	case float64:
+		return val
+	case string:
+		f, _ := strconv.ParseFloat(val, 64)
+		return f
+	}
+	return 0
+}
+
+func asStringList(v interface{}) []string {
+	var out []string
+	for _, item := range asList(v) {
+		out = append(out, asString(item))
+	}
+	if s, ok := v.(string); ok && s != "" {
+		out = []string{s}
+	}
+	return out
+}
+
+// asStringMap reads a compose mapping in map or "KEY=value" list form.
+func asStringMap(v interface{}) map[string]string {
+	out := make(map[string]string)
+	for k, val := range asMap(v) {
+		out[k] = asString(val)
+	}
+	for _, item := range asList(v) {
+		if k, val, ok := strings.Cut(asString(item), "="); ok {
+			out[k] = val
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,217 @@
+package recipe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testComposeFile = `
+services:
+  db:
+    image: postgres:16-alpine
+    environment:
+      - POSTGRES_USER=wiki
+      - POSTGRES_PASSWORD=changeme
+    volumes:
+      - db-data:/var/lib/postgresql/data
+    healthcheck:
+      test: pg_isready -U wiki
+  app:
+    image: ghcr.io/example/wiki:v2.5.1
+    restart: always
+    depends_on:
+      db:
+        condition: service_healthy
+    environment:
+      DB_HOST: db
+      DB_PASSWORD: changeme
+      SESSION_SECRET: abc123def
+      GITHUB_TOKEN: ${GITHUB_TOKEN}
+      SITE_URL: https://wiki.example.com/
+      PORT: 3000
+    ports:
+      - "8080:3000"
+    volumes:
+      - /uploads
+    privileged: true
+  worker:
+    image: ghcr.io/example/wiki-worker:v2.5.1
+    command: ["node", "worker.js"]
+    depends_on: [db, app]
+volumes:
+  db-data:
+secrets:
+  token:
+    file: ./token
+`
+
+func hasIssue(issues []LintIssue, level, service, fragment string) bool {
+	for _, i := range issues {
+		if i.Level == level && i.Service == service && strings.Contains(i.Message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestImport(t *testing.T) {
+	r, issues, err := Import([]byte(testComposeFile), ImportOptions{Source: "compose/docker-compose.yml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r.Name != "app" || r.Version != "2.5.1" || r.Image != "ghcr.io/example/wiki:v2.5.1" {
+		t.Fatalf("unexpected primary: name=%s version=%s image=%s", r.Name, r.Version, r.Image)
+	}
+	if r.Description != "Imported from docker-compose.yml" {
+		t.Fatalf("unexpected description %q", r.Description)
+	}
+	if len(r.Ports) != 1 || r.Ports[0] != 3000 {
+		t.Fatalf("expected container port 3000, got %v", r.Ports)
+	}
+	if r.Restart != "always" {
+		t.Fatalf("expected restart policy to carry over, got %q", r.Restart)
+	}
+
+	// Secrets: the literal shared by app and db becomes one generated value
+	if r.Environment["DB_PASSWORD"] != "auto_generate_32" || r.Environment["SESSION_SECRET"] != "auto_generate_64" {
+		t.Fatalf("expected literal secrets to be generated, got %v", r.Environment)
+	}
+	db := r.Services[0]
+	if db.Name != "db" || db.Environment["POSTGRES_PASSWORD"] != "${DB_PASSWORD}" {
+		t.Fatalf("expected db to share the generated password, got %+v", db.Environment)
+	}
+	if db.Condition != ConditionHealthy || db.HealthCheck == nil || db.HealthCheck.Test[0] != "CMD-SHELL" {
+		t.Fatalf("expected db healthcheck and condition, got %+v", db)
+	}
+
+	// Public hostnames become ${DOMAIN}; external credentials become prompts
+	if r.Environment["SITE_URL"] != "https://${DOMAIN}/" || r.Environment["DB_HOST"] != "db" {
+		t.Fatalf("unexpected host rewriting: %v", r.Environment)
+	}
+	if len(r.Prompts) != 2 || r.Prompts[0].Key != "DOMAIN" || r.Prompts[1].Key != "GITHUB_TOKEN" || !r.Prompts[1].Secret {
+		t.Fatalf("unexpected prompts: %+v", r.Prompts)
+	}
+
+	if r.Volumes[0] != "app_uploads:/uploads" {
+		t.Fatalf("expected anonymous volume to be named, got %v", r.Volumes)
+	}
+
+	worker := r.Services[1]
+	if worker.Command != "node worker.js" || !worker.Egress {
+		t.Fatalf("unexpected worker: %+v", worker)
+	}
+	if len(worker.DependsOn) != 1 || worker.DependsOn[0] != "db" {
+		t.Fatalf("expected dependency on the primary to be dropped, got %v", worker.DependsOn)
+	}
+
+	for _, want := range []struct{ level, service, fragment string }{
+		{LintWarn, "app", "privileged is not supported"},
+		{LintWarn, "compose", "top-level secrets"},
+		{LintWarn, "worker", "depends on the primary"},
+		{LintInfo, "app", "SITE_URL now uses ${DOMAIN}"},
+	} {
+		if !hasIssue(issues, want.level, want.service, want.fragment) {
+			t.Fatalf("expected %s issue for %s containing %q, got %+v", want.level, want.service, want.fragment, issues)
+		}
+	}
+
+	// Capabilities the images need once bunkr drops them all
+	if !reflect.DeepEqual(db.Security.CapAdd, startupCaps) || !hasIssue(issues, LintInfo, "db", "cap_add set to CHOWN") {
+		t.Fatalf("expected db to get the startup capabilities, got %v", db.Security.CapAdd)
+	}
+
+	if err := r.Validate(); err != nil {
+		t.Fatalf("expected imported recipe to validate: %v", err)
+	}
+}
+
+func TestImport_Primary(t *testing.T) {
+	compose := `
+services:
+  cache:
+    image: redis:7
+  api:
+    image: example/api:1.0
+    depends_on: [cache]
+  web:
+    image: example/web:1.0
+    expose: ["8080"]
+`
+	r, _, err := Import([]byte(compose), ImportOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Name != "api" {
+		t.Fatalf("expected the service with most dependencies, got %s", r.Name)
+	}
+
+	r, issues, err := Import([]byte(compose), ImportOptions{Primary: "web", Name: "My App"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Name != "my-app" || len(r.Ports) != 1 || r.Ports[0] != 8080 {
+		t.Fatalf("expected renamed primary on exposed port, got %s %v", r.Name, r.Ports)
+	}
+	if !hasIssue(issues, LintInfo, "my-app", "renamed") {
+		t.Fatalf("expected rename to be reported, got %+v", issues)
+	}
+
+	if _, _, err := Import([]byte(compose), ImportOptions{Primary: "missing"}); err == nil {
+		t.Fatal("expected error for unknown primary")
+	}
+	if _, _, err := Import([]byte("services:\n  db:\n    image: postgres:16\n"), ImportOptions{}); err == nil {
+		t.Fatal("expected error when no service looks like an app")
+	}
+}
+
+// Marshaling a recipe must not change what it means.
+func TestMarshalRecipe_RoundTrip(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("..", "..", "recipes", "*.yaml"))
+	for _, path := range files {
+		if filepath.Base(path) == "index.yaml" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := Parse(data)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		out, err := MarshalRecipe(want)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		got, err := Parse(out)
+		if err != nil {
+			t.Fatalf("%s: reparse: %v", path, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: recipe changed after marshaling:\n%s", path, out)
+		}
+	}
+}
+
+func TestImageVersion(t *testing.T) {
+	for image, want := range map[string]string{
+		"ghost:6.19.2":                       "6.19.2",
+		"ghost":                              "latest",
+		"docker.n8n.io/n8nio/n8n:2.12.2":     "2.12.2",
+		"registry.local:5000/app":            "latest",
+		"registry.local:5000/app:1.0":        "1.0",
+		"postgres:16-alpine@sha256:abc123":   "16-alpine",
+		"plausible/community-edition:v2.1.4": "2.1.4",
+		"example/app:vnext":                  "vnext",
+		"example/app:verbose":                "verbose",
+		"example/app:":                       "latest",
+	} {
+		if got := ImageVersion(image); got != want {
+			t.Errorf("ImageVersion(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
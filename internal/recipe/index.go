@@ -0,0 +1,130 @@
+package recipe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const indexHeader = "# Generated by `bunkr recipe index`. Do not edit by hand.\n"
+
+// BuildIndex parses and validates every recipe in dir and describes them
+// for the index, sorted by name. Pinned copies under <dir>/<name>/ become the
+// entry's versions. A detached <name>.yaml.sig next to a recipe is carried
+// into the index as its signature.
+func BuildIndex(dir string) ([]IndexEntry, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var index []IndexEntry
+	names := make(map[string]bool)
+	for _, path := range files {
+		if filepath.Base(path) == "index.yaml" {
+			continue
+		}
+		data, r, err := loadRecipeFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if want := strings.TrimSuffix(filepath.Base(path), ".yaml"); r.Name != want {
+			return nil, fmt.Errorf("%s: recipe is named %s, expected %s", path, r.Name, want)
+		}
+
+		entry := NewIndexEntry(r)
+		entry.Digest = Digest(data)
+		if sig, err := os.ReadFile(path + ".sig"); err == nil {
+			entry.Signature = strings.TrimSpace(string(sig))
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if entry.Versions, err = pinnedVersions(dir, r.Name); err != nil {
+			return nil, err
+		}
+		index = append(index, entry)
+		names[r.Name] = true
+	}
+
+	// A versions directory without a current recipe can't be installed
+	dirs, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dirs {
+		if d.IsDir() && !names[d.Name()] {
+			return nil, fmt.Errorf("%s: pinned versions without a %s.yaml recipe", filepath.Join(dir, d.Name()), d.Name())
+		}
+	}
+
+	sort.Slice(index, func(i, j int) bool { return index[i].Name < index[j].Name })
+	return index, nil
+}
+
+// pinnedVersions returns the versions kept under <dir>/<name>/, newest first,
+// checking that each file declares the version it is named after.
+func pinnedVersions(dir, name string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, name, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, path := range files {
+		_, r, err := loadRecipeFile(path)
+		if err != nil {
+			return nil, err
+		}
+		version := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		if r.Name != name || r.Version != version {
+			return nil, fmt.Errorf("%s: declares %s@%s, expected %s@%s", path, r.Name, r.Version, name, version)
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return CompareVersions(versions[i], versions[j]) > 0 })
+	return versions, nil
+}
+
+func loadRecipeFile(path string) ([]byte, *Recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, r, nil
+}
+
+// Digest returns the sha256 digest of a recipe file as "sha256:<hex>".
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// MarshalIndex renders an index the same way every time, so a stale
+// index.yaml shows up as a byte difference.
+func MarshalIndex(index []IndexEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(indexHeader)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(index); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+package recipe
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const indexTestRecipe = `name: %s
+version: "%s"
+description: Test app
+category: testing
+image: app:%s
+ports:
+  - 8080
+`
+
+func writeRecipe(t *testing.T, path, name, version string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := fmt.Sprintf(indexTestRecipe, name, version, version)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeRecipe(t, filepath.Join(dir, "zeta.yaml"), "zeta", "1.10.0")
+	writeRecipe(t, filepath.Join(dir, "zeta", "1.9.0.yaml"), "zeta", "1.9.0")
+	writeRecipe(t, filepath.Join(dir, "zeta", "1.10.0.yaml"), "zeta", "1.10.0")
+	writeRecipe(t, filepath.Join(dir, "alpha.yaml"), "alpha", "2.0.0")
+	os.WriteFile(filepath.Join(dir, "alpha.yaml.sig"), []byte("c2lnbmF0dXJl\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "index.yaml"), []byte("stale"), 0644)
+
+	index, err := BuildIndex(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(index) != 2 || index[0].Name != "alpha" || index[1].Name != "zeta" {
+		t.Fatalf("expected entries sorted by name, got %+v", index)
+	}
+	if index[0].Signature != "c2lnbmF0dXJl" {
+		t.Fatalf("expected signature from alpha.yaml.sig, got %q", index[0].Signature)
+	}
+	if index[1].Signature != "" {
+		t.Fatalf("expected no signature for zeta, got %q", index[1].Signature)
+	}
+	if got := strings.Join(index[1].Versions, ","); got != "1.10.0,1.9.0" {
+		t.Fatalf("expected versions newest first, got %s", got)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "alpha.yaml"))
+	if index[0].Digest != Digest(data) || !strings.HasPrefix(index[0].Digest, "sha256:") {
+		t.Fatalf("unexpected digest %q", index[0].Digest)
+	}
+
+	first, err := MarshalIndex(index)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, _ := BuildIndex(dir)
+	second, _ := MarshalIndex(again)
+	if !bytes.Equal(first, second) {
+		t.Fatal("expected identical output for the same directory")
+	}
+}
+
+func TestBuildIndex_Errors(t *testing.T) {
+	cases := map[string]func(dir string){
+		"recipe named after another file": func(dir string) {
+			writeRecipe(t, filepath.Join(dir, "alpha.yaml"), "beta", "1.0.0")
+		},
+		"pinned copy declares another version": func(dir string) {
+			writeRecipe(t, filepath.Join(dir, "alpha.yaml"), "alpha", "1.0.0")
+			writeRecipe(t, filepath.Join(dir, "alpha", "0.9.0.yaml"), "alpha", "1.0.0")
+		},
+		"versions without a current recipe": func(dir string) {
+			writeRecipe(t, filepath.Join(dir, "alpha.yaml"), "alpha", "1.0.0")
+			writeRecipe(t, filepath.Join(dir, "beta", "1.0.0.yaml"), "beta", "1.0.0")
+		},
+		"invalid recipe": func(dir string) {
+			os.WriteFile(filepath.Join(dir, "alpha.yaml"), []byte("name: alpha\nversion: \"1\"\n"), 0644)
+		},
+	}
+	for name, setup := range cases {
+		dir := t.TempDir()
+		setup(dir)
+		if _, err := BuildIndex(dir); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+// The committed index must match the recipes it describes.
+func TestShippedIndexUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "recipes")
+	index, err := BuildIndex(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := MarshalIndex(index)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "index.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("recipes/index.yaml is out of date, run: bunkr recipe index recipes")
+	}
+}
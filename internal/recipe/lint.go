@@ -0,0 +1,99 @@
+package recipe
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	LintWarn = "warn"
+	LintInfo = "info"
+)
+
+type LintIssue struct {
+	Level   string
+	Service string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Service, i.Message)
+}
+
+// Lint reports weak security settings in a recipe. Warnings flag settings
+// that loosen the baseline; info issues point out further hardening.
+func Lint(r *Recipe) []LintIssue {
+	issues := lintContainer(r.Name, r.Image, r.User, r.Security)
+	for _, svc := range r.Services {
+		issues = append(issues, lintContainer(svc.Name, svc.Image, svc.User, svc.Security)...)
+	}
+	return issues
+}
+
+func lintContainer(name, image, user string, sec Security) []LintIssue {
+	var issues []LintIssue
+	warn := func(format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Level: LintWarn, Service: name, Message: fmt.Sprintf(format, args...)})
+	}
+	info := func(format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Level: LintInfo, Service: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !sec.noNewPrivileges() {
+		warn("no_new_privileges is disabled")
+	}
+
+	dropsAll := false
+	for _, c := range sec.capDrop() {
+		if normalizeCap(c) == "ALL" {
+			dropsAll = true
+		}
+	}
+	if !dropsAll {
+		warn("cap_drop does not include ALL")
+	}
+	added := make(map[string]bool)
+	for _, c := range sec.CapAdd {
+		added[normalizeCap(c)] = true
+		if dangerousCaps[normalizeCap(c)] {
+			warn("cap_add grants %s", normalizeCap(c))
+		}
+	}
+	if dropsAll {
+		var missing []string
+		for _, c := range SuggestedCaps(image, user) {
+			if !added[c] {
+				missing = append(missing, c)
+			}
+		}
+		if len(missing) > 0 {
+			info("every capability is dropped; %s may need cap_add: [%s] (or security.baseline: false to keep Docker's defaults)", image, strings.Join(missing, ", "))
+		}
+	}
+
+	if sec.Seccomp == "unconfined" {
+		warn("seccomp is unconfined")
+	}
+	if sec.AppArmor == "unconfined" {
+		warn("apparmor is unconfined")
+	}
+
+	if strings.HasSuffix(image, ":latest") || !strings.Contains(imageTag(image), ":") {
+		warn("image %s is not pinned to a version", image)
+	}
+	if user == "" {
+		info("no user declared; container runs as the image default")
+	}
+	if !sec.ReadOnly {
+		info("root filesystem is writable")
+	}
+	return issues
+}
+
+// imageTag strips the registry host so a port in it isn't mistaken for a tag.
+func imageTag(image string) string {
+	if i := strings.LastIndex(image, "/"); i != -1 {
+		return image[i+1:]
+	}
+	return image
+}
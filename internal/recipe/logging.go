@@ -0,0 +1,60 @@
+package recipe
+
+import (
+	"fmt"
+	"strconv"
+)
+
+var defaultLogging = Logging{Driver: "json-file", MaxSize: "10m", MaxFile: 3}
+
+// Logging configures container log rotation. Unset fields fall back to the
+// app-wide setting, then to json-file with 3 x 10m files.
+type Logging struct {
+	Driver  string `yaml:"driver,omitempty"`
+	MaxSize string `yaml:"max_size,omitempty"`
+	MaxFile int    `yaml:"max_file,omitempty"`
+}
+
+// Merge returns l with every non-zero field of o taking precedence.
+func (l Logging) Merge(o Logging) Logging {
+	if o.Driver != "" {
+		l.Driver = o.Driver
+	}
+	if o.MaxSize != "" {
+		l.MaxSize = o.MaxSize
+	}
+	if o.MaxFile != 0 {
+		l.MaxFile = o.MaxFile
+	}
+	return l
+}
+
+func (l Logging) Validate() error {
+	switch l.Driver {
+	case "", "json-file", "local":
+	default:
+		return fmt.Errorf("unsupported logging driver %q (expected json-file or local)", l.Driver)
+	}
+	if l.MaxSize != "" && !memoryPattern.MatchString(l.MaxSize) {
+		return fmt.Errorf("invalid log max_size %q (expected e.g. 10m)", l.MaxSize)
+	}
+	if l.MaxFile < 0 {
+		return fmt.Errorf("invalid log max_file %d", l.MaxFile)
+	}
+	return nil
+}
+
+type composeLogging struct {
+	Driver  string            `yaml:"driver,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
+}
+
+func (l Logging) compose() *composeLogging {
+	return &composeLogging{
+		Driver: l.Driver,
+		Options: map[string]string{
+			"max-size": l.MaxSize,
+			"max-file": strconv.Itoa(l.MaxFile),
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package recipe
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateCompose_LoggingDefaults(t *testing.T) {
+	r := &Recipe{
+		Name:     "plausible",
+		Image:    "plausible:2.1.4",
+		Ports:    []int{8000},
+		Logging:  Logging{MaxSize: "50m"},
+		Services: []Service{{Name: "plausible_events_db", Image: "clickhouse:24", Logging: Logging{Driver: "local", MaxFile: 5}}},
+	}
+
+	out, err := GenerateCompose(r, nil, 8000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cf composeFile
+	if err := yaml.Unmarshal(out, &cf); err != nil {
+		t.Fatalf("generated compose is not valid YAML: %v", err)
+	}
+
+	primary := cf.Services["plausible"].Logging
+	if primary.Driver != "json-file" || primary.Options["max-size"] != "50m" || primary.Options["max-file"] != "3" {
+		t.Fatalf("unexpected primary logging: %+v", primary)
+	}
+	events := cf.Services["plausible_events_db"].Logging
+	if events.Driver != "local" || events.Options["max-size"] != "50m" || events.Options["max-file"] != "5" {
+		t.Fatalf("unexpected service logging: %+v", events)
+	}
+}
+
+func TestApplySettings(t *testing.T) {
+	r := &Recipe{
+		Logging:  Logging{MaxSize: "20m"},
+		Services: []Service{{Name: "db", Logging: Logging{MaxSize: "5m", MaxFile: 2}}},
+	}
+	settings, err := ParseSettings([]string{"logging.max_size=100m", "logging.driver=local"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ApplySettings(r, settings); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r.Logging != (Logging{Driver: "local", MaxSize: "100m"}) {
+		t.Fatalf("unexpected app logging: %+v", r.Logging)
+	}
+	if r.Services[0].Logging != (Logging{Driver: "local", MaxSize: "100m", MaxFile: 2}) {
+		t.Fatalf("unexpected service logging: %+v", r.Services[0].Logging)
+	}
+}
+
+func TestApplySettings_Invalid(t *testing.T) {
+	cases := []map[string]string{
+		{"logging.driver": "syslog"},
+		{"logging.max_file": "zero"},
+		{"logging.max_size": "big"},
+		{"unknown.key": "1"},
+	}
+	for _, settings := range cases {
+		if err := ApplySettings(&Recipe{}, settings); err == nil {
+			t.Fatalf("expected %v to be rejected", settings)
+		}
+	}
+
+	if _, err := ParseSettings([]string{"novalue"}); err == nil {
+		t.Fatal("expected error for pair without '='")
+	}
+}
@@ -7,48 +7,127 @@ import (
 )
 
 type Recipe struct {
-	Name        string            `yaml:"name"`
-	Version     string            `yaml:"version"`
-	Description string            `yaml:"description"`
-	Image       string            `yaml:"image"`
-	Private     bool              `yaml:"private"`
-	Prompts     []Prompt          `yaml:"prompts"`
-	Ports       []int             `yaml:"ports"`
-	Volumes     []string          `yaml:"volumes"`
-	Services    []Service         `yaml:"services"`
-	Environment map[string]string `yaml:"environment"`
-	Command     string            `yaml:"command"`
-	InitCommand string            `yaml:"init_command"`
-	PostInit    []string          `yaml:"post_init"`
-	HealthCheck *HealthCheck      `yaml:"health_check"`
-	Display     []DisplayVar      `yaml:"display"`
+	Name         string            `yaml:"name"`
+	Version      string            `yaml:"version"`
+	Description  string            `yaml:"description,omitempty"`
+	Category     string            `yaml:"category,omitempty"`
+	Tags         []string          `yaml:"tags,omitempty"`
+	Homepage     string            `yaml:"homepage,omitempty"`
+	Image        string            `yaml:"image"`
+	Private      bool              `yaml:"private,omitempty"`
+	Prompts      []Prompt          `yaml:"prompts,omitempty"`
+	Ports        []int             `yaml:"ports,omitempty"`
+	Endpoints    []Endpoint        `yaml:"endpoints,omitempty"`
+	Volumes      []string          `yaml:"volumes,omitempty"`
+	Services     []Service         `yaml:"services,omitempty"`
+	Files        []File            `yaml:"files,omitempty"`
+	Environment  map[string]string `yaml:"environment,omitempty"`
+	Command      string            `yaml:"command,omitempty"`
+	User         string            `yaml:"user,omitempty"`
+	Tmpfs        []string          `yaml:"tmpfs,omitempty"`
+	Security     Security          `yaml:"security,omitempty"`
+	Resources    Resources         `yaml:"resources,omitempty"`
+	Restart      string            `yaml:"restart,omitempty"`
+	Logging      Logging           `yaml:"logging,omitempty"`
+	Requirements Requirements      `yaml:"requirements,omitempty"`
+	InitCommand  string            `yaml:"init_command,omitempty"`
+	PostInit     []string          `yaml:"post_init,omitempty"`
+	Hooks        Hooks             `yaml:"hooks,omitempty"`
+	Migrations   []Migration       `yaml:"migrations,omitempty"`
+	HealthCheck  *HealthCheck      `yaml:"health_check,omitempty"`
+	Display      []DisplayVar      `yaml:"display,omitempty"`
 }
 
 type Prompt struct {
 	Key      string   `yaml:"key"`
-	Label    string   `yaml:"label"`
-	Required bool     `yaml:"required"`
-	Default  string   `yaml:"default"`
-	Secret   bool     `yaml:"secret"`
-	Options  []string `yaml:"options"`
+	Label    string   `yaml:"label,omitempty"`
+	Required bool     `yaml:"required,omitempty"`
+	Default  string   `yaml:"default,omitempty"`
+	Secret   bool     `yaml:"secret,omitempty"`
+	Options  []string `yaml:"options,omitempty"`
 }
 
 type DisplayVar struct {
 	Key   string `yaml:"key"`
-	Label string `yaml:"label"`
+	Label string `yaml:"label,omitempty"`
 }
 
 type Service struct {
-	Name        string            `yaml:"name"`
-	Image       string            `yaml:"image"`
-	Environment map[string]string `yaml:"environment"`
-	Volumes     []string          `yaml:"volumes"`
+	Name        string                `yaml:"name"`
+	Image       string                `yaml:"image"`
+	Command     string                `yaml:"command,omitempty"`
+	Entrypoint  string                `yaml:"entrypoint,omitempty"`
+	Environment map[string]string     `yaml:"environment,omitempty"`
+	Volumes     []string              `yaml:"volumes,omitempty"`
+	HealthCheck *ContainerHealthCheck `yaml:"healthcheck,omitempty"`
+	Ulimits     map[string]Ulimit     `yaml:"ulimits,omitempty"`
+	Tmpfs       []string              `yaml:"tmpfs,omitempty"`
+	User        string                `yaml:"user,omitempty"`
+	WorkingDir  string                `yaml:"working_dir,omitempty"`
+	Labels      map[string]string     `yaml:"labels,omitempty"`
+	ExtraHosts  []string              `yaml:"extra_hosts,omitempty"`
+	DependsOn   []string              `yaml:"depends_on,omitempty"`
+	Egress      bool                  `yaml:"egress,omitempty"` // allow outbound access from the internal network
+	Condition   string                `yaml:"condition,omitempty"`
+	Security    Security              `yaml:"security,omitempty"`
+	Resources   Resources             `yaml:"resources,omitempty"`
+	Restart     string                `yaml:"restart,omitempty"`
+	Logging     Logging               `yaml:"logging,omitempty"`
+}
+
+// ContainerHealthCheck is a compose-level healthcheck run by Docker inside
+// the container, as opposed to HealthCheck which bunkr polls from the host.
+type ContainerHealthCheck struct {
+	Test        []string `yaml:"test"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	Retries     int      `yaml:"retries,omitempty"`
+	StartPeriod string   `yaml:"start_period,omitempty"`
+}
+
+// Ulimit accepts either a single number (soft = hard) or a soft/hard pair,
+// matching the compose syntax.
+type Ulimit struct {
+	Soft int `yaml:"soft"`
+	Hard int `yaml:"hard"`
+}
+
+func (u *Ulimit) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var n int
+		if err := node.Decode(&n); err != nil {
+			return err
+		}
+		u.Soft, u.Hard = n, n
+		return nil
+	}
+	type plain Ulimit
+	return node.Decode((*plain)(u))
+}
+
+// Dependency conditions accepted in a service's condition field.
+const (
+	ConditionStarted   = "service_started"
+	ConditionHealthy   = "service_healthy"
+	ConditionCompleted = "service_completed_successfully"
+)
+
+// ReadyCondition returns the condition dependents wait for before starting.
+// Services with a healthcheck default to service_healthy.
+func (s Service) ReadyCondition() string {
+	if s.Condition != "" {
+		return s.Condition
+	}
+	if s.HealthCheck != nil {
+		return ConditionHealthy
+	}
+	return ConditionStarted
 }
 
 type HealthCheck struct {
 	URL      string `yaml:"url"`
-	Timeout  int    `yaml:"timeout"`
-	Interval int    `yaml:"interval"`
+	Timeout  int    `yaml:"timeout,omitempty"`
+	Interval int    `yaml:"interval,omitempty"`
 }
 
 func Parse(data []byte) (*Recipe, error) {
@@ -77,8 +156,89 @@ func (r *Recipe) Validate() error {
 	if r.Image == "" {
 		return fmt.Errorf("recipe image is required")
 	}
-	if len(r.Ports) == 0 {
+	if len(r.Ports) == 0 && len(r.Endpoints) == 0 {
 		return fmt.Errorf("recipe must expose at least one port")
 	}
+	if err := r.validateEndpoints(); err != nil {
+		return err
+	}
+
+	if err := r.Resources.Validate(); err != nil {
+		return err
+	}
+	if err := ValidateRestart(r.Restart); err != nil {
+		return err
+	}
+	if err := r.Logging.Validate(); err != nil {
+		return err
+	}
+	if err := r.Requirements.Validate(); err != nil {
+		return err
+	}
+
+	names := map[string]bool{r.Name: true}
+	for _, svc := range r.Services {
+		if svc.Name == "" {
+			return fmt.Errorf("service name is required")
+		}
+		if names[svc.Name] {
+			return fmt.Errorf("duplicate service name %s", svc.Name)
+		}
+		names[svc.Name] = true
+		if svc.Image == "" {
+			return fmt.Errorf("service %s: image is required", svc.Name)
+		}
+		if err := svc.Resources.Validate(); err != nil {
+			return fmt.Errorf("service %s: %w", svc.Name, err)
+		}
+		if err := ValidateRestart(svc.Restart); err != nil {
+			return fmt.Errorf("service %s: %w", svc.Name, err)
+		}
+		if err := svc.Logging.Validate(); err != nil {
+			return fmt.Errorf("service %s: %w", svc.Name, err)
+		}
+		switch svc.Condition {
+		case "", ConditionStarted, ConditionHealthy, ConditionCompleted:
+		default:
+			return fmt.Errorf("service %s: unknown condition %q", svc.Name, svc.Condition)
+		}
+		if svc.Condition == ConditionHealthy && svc.HealthCheck == nil {
+			return fmt.Errorf("service %s: condition service_healthy requires a healthcheck", svc.Name)
+		}
+	}
+	if err := r.validateHooks(names); err != nil {
+		return err
+	}
+	for _, f := range r.Files {
+		if err := f.validate(); err != nil {
+			return err
+		}
+		for _, svc := range f.Services {
+			if !names[svc] {
+				return fmt.Errorf("file %s is mounted into unknown service %s", f.Path, svc)
+			}
+		}
+		// A file mounted over one in a volume can't be replaced by the app,
+		// which breaks saving it with a rename
+		mounts := map[string][]string{r.Name: r.Volumes}
+		for _, svc := range r.Services {
+			mounts[svc.Name] = svc.Volumes
+		}
+		for svc, volumes := range mounts {
+			if !f.mountedInto(svc, svc == r.Name) {
+				continue
+			}
+			if v := volumeAt(volumes, f.Mount); v != "" {
+				return fmt.Errorf("file %s: mount %s is inside volume %s; mount it outside the volume", f.Path, f.Mount, v)
+			}
+		}
+	}
+	for _, svc := range r.Services {
+		for _, dep := range svc.DependsOn {
+			if !names[dep] || dep == r.Name {
+				return fmt.Errorf("service %s depends on unknown service %s", svc.Name, dep)
+			}
+		}
+	}
 	return nil
 }
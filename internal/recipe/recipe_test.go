@@ -289,3 +289,63 @@ func TestValidateRecipe(t *testing.T) {
 		t.Fatal("expected validation error for empty image")
 	}
 }
+
+func TestParseRecipe_ServiceOptions(t *testing.T) {
+	yamlContent := `
+name: test-app
+version: "1.0.0"
+image: test:latest
+ports:
+  - 8080
+services:
+  - name: db
+    image: postgres:16-alpine
+    healthcheck:
+      test: ["CMD-SHELL", "pg_isready"]
+      interval: 5s
+      retries: 5
+    ulimits:
+      nproc: 65535
+      nofile:
+        soft: 1024
+        hard: 2048
+`
+	r, err := Parse([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	svc := r.Services[0]
+	if svc.Ulimits["nproc"] != (Ulimit{Soft: 65535, Hard: 65535}) {
+		t.Fatalf("expected scalar ulimit to set soft and hard, got %+v", svc.Ulimits["nproc"])
+	}
+	if svc.Ulimits["nofile"] != (Ulimit{Soft: 1024, Hard: 2048}) {
+		t.Fatalf("unexpected nofile ulimit: %+v", svc.Ulimits["nofile"])
+	}
+	if svc.ReadyCondition() != ConditionHealthy {
+		t.Fatalf("expected service_healthy, got %s", svc.ReadyCondition())
+	}
+}
+
+func TestValidate_ServiceDependencies(t *testing.T) {
+	r := &Recipe{
+		Name:    "app",
+		Version: "1.0.0",
+		Image:   "app:latest",
+		Ports:   []int{80},
+		Services: []Service{
+			{Name: "worker", Image: "app:latest", DependsOn: []string{"cache"}},
+		},
+	}
+	if err := r.Validate(); err == nil {
+		t.Fatal("expected error for unknown dependency")
+	}
+
+	r.Services[0].DependsOn = nil
+	r.Services[0].Condition = ConditionHealthy
+	if err := r.Validate(); err == nil {
+		t.Fatal("expected error for service_healthy without healthcheck")
+	}
+}
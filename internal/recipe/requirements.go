@@ -0,0 +1,99 @@
+package recipe
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var kernelFeaturePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Requirements describe what a host needs to run the recipe. They are
+// checked before install; zero values mean "no requirement".
+type Requirements struct {
+	Memory        string   `yaml:"memory,omitempty"`        // minimum total RAM, e.g. 2g
+	Disk          string   `yaml:"disk,omitempty"`          // minimum free disk for images and data
+	Architectures []string `yaml:"architectures,omitempty"` // e.g. amd64, arm64
+	Kernel        []string `yaml:"kernel,omitempty"`        // filesystems or modules, e.g. overlay
+}
+
+func (r Requirements) Validate() error {
+	if r.Memory != "" {
+		if _, err := ParseSize(r.Memory); err != nil {
+			return fmt.Errorf("requirements: memory: %w", err)
+		}
+	}
+	if r.Disk != "" {
+		if _, err := ParseSize(r.Disk); err != nil {
+			return fmt.Errorf("requirements: disk: %w", err)
+		}
+	}
+	for _, a := range r.Architectures {
+		if NormalizeArch(a) == "" {
+			return fmt.Errorf("requirements: unknown architecture %q", a)
+		}
+	}
+	for _, k := range r.Kernel {
+		if !kernelFeaturePattern.MatchString(k) {
+			return fmt.Errorf("requirements: invalid kernel feature %q", k)
+		}
+	}
+	return nil
+}
+
+// SupportsArch reports whether arch (as printed by uname -m or in Go/Docker
+// form) is one of the required architectures. No list means any.
+func (r Requirements) SupportsArch(arch string) bool {
+	if len(r.Architectures) == 0 {
+		return true
+	}
+	arch = NormalizeArch(arch)
+	for _, a := range r.Architectures {
+		if NormalizeArch(a) == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeArch maps kernel and Docker architecture names to Docker's
+// (amd64, arm64, ...). Returns "" for unknown names.
+func NormalizeArch(arch string) string {
+	switch strings.ToLower(arch) {
+	case "amd64", "x86_64":
+		return "amd64"
+	case "arm64", "aarch64":
+		return "arm64"
+	case "arm", "armv7", "armv7l", "armhf":
+		return "arm"
+	case "386", "i386", "i686":
+		return "386"
+	case "ppc64le", "s390x", "riscv64":
+		return strings.ToLower(arch)
+	}
+	return ""
+}
+
+// ParseSize converts a size such as 512m or 2g into bytes, using the same
+// binary units as Docker.
+func ParseSize(s string) (int64, error) {
+	if !memoryPattern.MatchString(s) {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512m or 1g)", s)
+	}
+	num := strings.TrimRight(strings.ToLower(s), "bkmg")
+	unit := strings.TrimSuffix(strings.ToLower(s[len(num):]), "b")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	switch unit {
+	case "k":
+		n *= 1 << 10
+	case "m":
+		n *= 1 << 20
+	case "g":
+		n *= 1 << 30
+	}
+	return int64(n), nil
+}
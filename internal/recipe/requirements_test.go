@@ -0,0 +1,52 @@
+package recipe
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"512m":  512 << 20,
+		"2g":    2 << 30,
+		"1.5G":  3 << 29,
+		"64kb":  64 << 10,
+		"1024":  1024,
+		"100mb": 100 << 20,
+	}
+	for in, want := range cases {
+		got, err := ParseSize(in)
+		if err != nil {
+			t.Fatalf("ParseSize(%q): unexpected error: %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("ParseSize(%q) = %d, want %d", in, got, want)
+		}
+	}
+	if _, err := ParseSize("lots"); err == nil {
+		t.Fatal("expected error for invalid size")
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	req := Requirements{Memory: "2g", Disk: "10g", Architectures: []string{"amd64", "arm64"}, Kernel: []string{"overlay"}}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !req.SupportsArch("x86_64") || !req.SupportsArch("aarch64") {
+		t.Fatal("expected uname architectures to be normalized")
+	}
+	if req.SupportsArch("armv7l") {
+		t.Fatal("expected arm to be unsupported")
+	}
+	if !(Requirements{}).SupportsArch("riscv64") {
+		t.Fatal("expected any architecture without a list")
+	}
+
+	for _, bad := range []Requirements{
+		{Memory: "two gigs"},
+		{Architectures: []string{"sparc"}},
+		{Kernel: []string{"overlay; rm -rf /"}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package recipe
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultRestart = "unless-stopped"
+
+var memoryPattern = regexp.MustCompile(`(?i)^[0-9]+(\.[0-9]+)?[bkmg]?b?$`)
+
+// Resources caps what a single container may consume. Zero values mean
+// "no limit".
+type Resources struct {
+	Memory string  `yaml:"memory,omitempty"`
+	CPUs   float64 `yaml:"cpus,omitempty"`
+	Pids   int     `yaml:"pids,omitempty"`
+}
+
+func (r Resources) IsZero() bool {
+	return r == Resources{}
+}
+
+// Merge returns r with every non-zero field of o taking precedence.
+func (r Resources) Merge(o Resources) Resources {
+	if o.Memory != "" {
+		r.Memory = o.Memory
+	}
+	if o.CPUs != 0 {
+		r.CPUs = o.CPUs
+	}
+	if o.Pids != 0 {
+		r.Pids = o.Pids
+	}
+	return r
+}
+
+func (r Resources) Validate() error {
+	if r.Memory != "" && !memoryPattern.MatchString(r.Memory) {
+		return fmt.Errorf("invalid memory limit %q (expected e.g. 512m or 1g)", r.Memory)
+	}
+	if r.CPUs < 0 {
+		return fmt.Errorf("invalid cpu limit %v", r.CPUs)
+	}
+	if r.Pids < 0 {
+		return fmt.Errorf("invalid pids limit %d", r.Pids)
+	}
+	return nil
+}
+
+func (r Resources) String() string {
+	var parts []string
+	if r.Memory != "" {
+		parts = append(parts, "memory="+r.Memory)
+	}
+	if r.CPUs != 0 {
+		parts = append(parts, "cpus="+strconv.FormatFloat(r.CPUs, 'f', -1, 64))
+	}
+	if r.Pids != 0 {
+		parts = append(parts, fmt.Sprintf("pids=%d", r.Pids))
+	}
+	if len(parts) == 0 {
+		return "unlimited"
+	}
+	return strings.Join(parts, " ")
+}
+
+// ValidateRestart checks a compose restart policy.
+func ValidateRestart(policy string) error {
+	switch policy {
+	case "", "no", "always", "unless-stopped", "on-failure":
+		return nil
+	}
+	if n, ok := strings.CutPrefix(policy, "on-failure:"); ok {
+		if _, err := strconv.Atoi(n); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid restart policy %q", policy)
+}
+
+func restartPolicy(policy string) string {
+	if policy == "" {
+		return defaultRestart
+	}
+	return policy
+}
@@ -0,0 +1,76 @@
+package recipe
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResourcesMerge(t *testing.T) {
+	base := Resources{Memory: "1g", CPUs: 2, Pids: 200}
+	merged := base.Merge(Resources{Memory: "512m"})
+
+	if merged.Memory != "512m" {
+		t.Fatalf("expected override memory 512m, got %s", merged.Memory)
+	}
+	if merged.CPUs != 2 || merged.Pids != 200 {
+		t.Fatalf("expected unset fields to keep defaults, got %+v", merged)
+	}
+}
+
+func TestResourcesValidate(t *testing.T) {
+	valid := []Resources{{}, {Memory: "512m"}, {Memory: "1G"}, {Memory: "1.5gb"}, {CPUs: 0.5, Pids: 100}}
+	for _, r := range valid {
+		if err := r.Validate(); err != nil {
+			t.Fatalf("expected %+v to be valid, got %v", r, err)
+		}
+	}
+
+	invalid := []Resources{{Memory: "lots"}, {Memory: "512x"}, {CPUs: -1}, {Pids: -5}}
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Fatalf("expected %+v to be invalid", r)
+		}
+	}
+}
+
+func TestValidateRestart(t *testing.T) {
+	for _, p := range []string{"", "no", "always", "unless-stopped", "on-failure", "on-failure:5"} {
+		if err := ValidateRestart(p); err != nil {
+			t.Fatalf("expected %q to be valid, got %v", p, err)
+		}
+	}
+	for _, p := range []string{"sometimes", "on-failure:x"} {
+		if err := ValidateRestart(p); err == nil {
+			t.Fatalf("expected %q to be invalid", p)
+		}
+	}
+}
+
+func TestGenerateCompose_Resources(t *testing.T) {
+	r := &Recipe{
+		Name:      "plausible",
+		Image:     "plausible:latest",
+		Ports:     []int{8000},
+		Resources: Resources{Memory: "512m", CPUs: 1.5, Pids: 256},
+		Restart:   "always",
+		Services: []Service{
+			{
+				Name:      "plausible_events_db",
+				Image:     "clickhouse/clickhouse-server:24.3-alpine",
+				Resources: Resources{Memory: "1g"},
+			},
+		},
+	}
+
+	out, err := GenerateCompose(r, nil, 8000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := string(out)
+
+	for _, want := range []string{"mem_limit: 512m", "cpus: 1.5", "pids_limit: 256", "restart: always", "mem_limit: 1g", "restart: unless-stopped"} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("expected %q in compose output:\n%s", want, s)
+		}
+	}
+}
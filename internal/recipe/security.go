@@ -0,0 +1,103 @@
+package recipe
+
+import "strings"
+
+// Security holds per-container hardening settings. The zero value is the
+// secure baseline: no-new-privileges on and every capability dropped.
+// Recipes relax it by setting no_new_privileges: false, an explicit
+// cap_drop list or cap_add, or turn it off with baseline: false so the
+// container keeps Docker's default capabilities.
+type Security struct {
+	Baseline        *bool    `yaml:"baseline,omitempty"`
+	NoNewPrivileges *bool    `yaml:"no_new_privileges,omitempty"`
+	CapDrop         []string `yaml:"cap_drop,omitempty"`
+	CapAdd          []string `yaml:"cap_add,omitempty"`
+	ReadOnly        bool     `yaml:"read_only,omitempty"`
+	Seccomp         string   `yaml:"seccomp,omitempty"`  // profile path or "unconfined"
+	AppArmor        string   `yaml:"apparmor,omitempty"` // profile name or "unconfined"
+}
+
+func (s Security) baseline() bool {
+	return s.Baseline == nil || *s.Baseline
+}
+
+func (s Security) noNewPrivileges() bool {
+	if s.NoNewPrivileges != nil {
+		return *s.NoNewPrivileges
+	}
+	return s.baseline()
+}
+
+func (s Security) capDrop() []string {
+	if s.CapDrop != nil || !s.baseline() {
+		return s.CapDrop
+	}
+	return []string{"ALL"}
+}
+
+// startupCaps are needed by images that start as root to set up their
+// data directories and then switch to an unprivileged user, which is most
+// official images.
+var startupCaps = []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "SETGID", "SETUID"}
+
+// imageCaps are further capabilities particular images need, by repository.
+var imageCaps = map[string][]string{
+	"clickhouse/clickhouse-server": {"IPC_LOCK", "SYS_NICE"},
+}
+
+// SuggestedCaps returns the capabilities a container of image usually needs
+// once every capability is dropped. user is the recipe's user, if any.
+func SuggestedCaps(image, user string) []string {
+	var caps []string
+	if user == "" {
+		caps = append(caps, startupCaps...)
+	}
+	return append(caps, imageCaps[imageRepo(image)]...)
+}
+
+// imageRepo strips the tag and digest from an image reference.
+func imageRepo(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+func (s Security) securityOpts() []string {
+	var opts []string
+	if s.noNewPrivileges() {
+		opts = append(opts, "no-new-privileges:true")
+	}
+	if s.Seccomp != "" {
+		opts = append(opts, "seccomp="+s.Seccomp)
+	}
+	if s.AppArmor != "" {
+		opts = append(opts, "apparmor="+s.AppArmor)
+	}
+	return opts
+}
+
+func (s *composeService) applySecurity(sec Security) {
+	s.SecurityOpt = sec.securityOpts()
+	s.CapDrop = sec.capDrop()
+	s.CapAdd = sec.CapAdd
+	s.ReadOnly = sec.ReadOnly
+}
+
+// dangerousCaps grant enough to escape or reconfigure the host.
+var dangerousCaps = map[string]bool{
+	"ALL":             true,
+	"SYS_ADMIN":       true,
+	"SYS_MODULE":      true,
+	"SYS_PTRACE":      true,
+	"SYS_RAWIO":       true,
+	"NET_ADMIN":       true,
+	"DAC_READ_SEARCH": true,
+	"SYS_BOOT":        true,
+	"MAC_ADMIN":       true,
+}
+
+func normalizeCap(c string) string {
+	return strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+}
@@ -0,0 +1,162 @@
+package recipe
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateCompose_SecurityDefaults(t *testing.T) {
+	r := &Recipe{
+		Name:  "app",
+		Image: "app:1.0",
+		Ports: []int{8080},
+		Services: []Service{
+			{Name: "db", Image: "postgres:16-alpine"},
+		},
+	}
+
+	out, err := GenerateCompose(r, nil, 8080)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := string(out)
+
+	if strings.Count(s, "no-new-privileges:true") != 2 {
+		t.Fatalf("expected no-new-privileges on both services, got:\n%s", s)
+	}
+	if strings.Count(s, "- ALL") != 2 {
+		t.Fatalf("expected cap_drop ALL on both services, got:\n%s", s)
+	}
+	if strings.Contains(s, "read_only") {
+		t.Fatalf("expected writable root filesystem by default, got:\n%s", s)
+	}
+}
+
+func TestGenerateCompose_SecurityOverrides(t *testing.T) {
+	off := false
+	r := &Recipe{
+		Name:  "app",
+		Image: "app:1.0",
+		Ports: []int{8080},
+		User:  "1000:1000",
+		Tmpfs: []string{"/tmp"},
+		Security: Security{
+			CapAdd:   []string{"NET_BIND_SERVICE"},
+			ReadOnly: true,
+			Seccomp:  "/etc/bunkr/seccomp.json",
+			AppArmor: "docker-default",
+		},
+		Services: []Service{
+			{Name: "legacy", Image: "legacy:1.0", Security: Security{NoNewPrivileges: &off, CapDrop: []string{}}},
+		},
+	}
+
+	out, err := GenerateCompose(r, nil, 8080)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := string(out)
+
+	for _, want := range []string{"user: 1000:1000", "read_only: true", "- /tmp", "- NET_BIND_SERVICE", "seccomp=/etc/bunkr/seccomp.json", "apparmor=docker-default"} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("expected %q in compose output:\n%s", want, s)
+		}
+	}
+	legacy := s[strings.Index(s, "legacy:"):]
+	if strings.Contains(legacy, "no-new-privileges") || strings.Contains(legacy, "cap_drop") {
+		t.Fatalf("expected relaxed service to skip defaults, got:\n%s", legacy)
+	}
+}
+
+func TestGenerateCompose_SecurityBaselineOff(t *testing.T) {
+	off := false
+	r := &Recipe{Name: "app", Image: "app:1.0", Ports: []int{8080}, Security: Security{Baseline: &off}}
+
+	// The opt-out has to survive being written back out
+	data, err := MarshalRecipe(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := GenerateCompose(r, nil, 8080)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := string(out); strings.Contains(s, "cap_drop") || strings.Contains(s, "no-new-privileges") {
+		t.Fatalf("expected Docker's defaults with the baseline off, got:\n%s", s)
+	}
+}
+
+func TestLint_SuggestedCaps(t *testing.T) {
+	r := &Recipe{
+		Name:     "app",
+		Image:    "app:1.0",
+		Security: Security{CapAdd: []string{"CHOWN", "SETUID"}},
+		Services: []Service{{Name: "events", Image: "clickhouse/clickhouse-server:24.3-alpine", User: "101"}},
+	}
+	issues := Lint(r)
+	if !hasIssue(issues, LintInfo, "app", "may need cap_add: [DAC_OVERRIDE, FOWNER, SETGID]") {
+		t.Fatalf("expected the missing startup caps to be suggested, got %+v", issues)
+	}
+	if !hasIssue(issues, LintInfo, "events", "may need cap_add: [IPC_LOCK, SYS_NICE]") {
+		t.Fatalf("expected clickhouse's caps to be suggested, got %+v", issues)
+	}
+}
+
+func TestLint(t *testing.T) {
+	off := false
+	r := &Recipe{
+		Name:     "app",
+		Image:    "app:latest",
+		User:     "app",
+		Security: Security{ReadOnly: true},
+		Services: []Service{
+			{
+				Name:  "db",
+				Image: "postgres:16",
+				Security: Security{
+					NoNewPrivileges: &off,
+					CapDrop:         []string{"NET_RAW"},
+					CapAdd:          []string{"cap_sys_admin"},
+					Seccomp:         "unconfined",
+				},
+			},
+		},
+	}
+
+	var warnings []string
+	for _, issue := range Lint(r) {
+		if issue.Level == LintWarn {
+			warnings = append(warnings, issue.String())
+		}
+	}
+	got := strings.Join(warnings, "\n")
+
+	for _, want := range []string{
+		"app: image app:latest is not pinned",
+		"db: no_new_privileges is disabled",
+		"db: cap_drop does not include ALL",
+		"db: cap_add grants SYS_ADMIN",
+		"db: seccomp is unconfined",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected warning %q, got:\n%s", want, got)
+		}
+	}
+	if len(warnings) != 5 {
+		t.Fatalf("expected 5 warnings, got %d:\n%s", len(warnings), got)
+	}
+}
+
+func TestLint_RegistryPort(t *testing.T) {
+	r := &Recipe{Name: "app", Image: "registry.local:5000/app"}
+	for _, issue := range Lint(r) {
+		if strings.Contains(issue.Message, "not pinned") {
+			return
+		}
+	}
+	t.Fatal("expected untagged image behind a registry port to be flagged")
+}
@@ -0,0 +1,51 @@
+package recipe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSettings turns key=value pairs from --set flags into a map.
+func ParseSettings(pairs []string) (map[string]string, error) {
+	settings := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid --set %q (expected key=value)", pair)
+		}
+		settings[k] = v
+	}
+	return settings, nil
+}
+
+// ApplySettings applies --set overrides to every service in the recipe.
+// Supported keys: logging.driver, logging.max_size, logging.max_file.
+func ApplySettings(r *Recipe, settings map[string]string) error {
+	var logging Logging
+	for k, v := range settings {
+		switch k {
+		case "logging.driver":
+			logging.Driver = v
+		case "logging.max_size":
+			logging.MaxSize = v
+		case "logging.max_file":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid logging.max_file %q", v)
+			}
+			logging.MaxFile = n
+		default:
+			return fmt.Errorf("unknown setting %q", k)
+		}
+	}
+	if err := logging.Validate(); err != nil {
+		return err
+	}
+
+	r.Logging = r.Logging.Merge(logging)
+	for i := range r.Services {
+		r.Services[i].Logging = r.Services[i].Logging.Merge(logging)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package recipe
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseRef splits a "name@version" argument. Version is empty when the
+// argument has no @, meaning the latest recipe.
+func ParseRef(ref string) (name, version string) {
+	if idx := strings.LastIndex(ref, "@"); idx != -1 {
+		return ref[:idx], ref[idx+1:]
+	}
+	return ref, ""
+}
+
+// versionParts parses the leading numeric components of a version such as
+// "v2.1.4" or "16-alpine". ok is false for versions like "latest".
+func versionParts(v string) (parts []int, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i != -1 {
+		v = v[:i]
+	}
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, len(parts) > 0
+}
+
+// CompareVersions returns -1, 0 or 1 as a is older than, equal to or newer
+// than b. Versions that aren't numeric compare as equal only when identical;
+// otherwise they sort after numeric ones, then lexically.
+func CompareVersions(a, b string) int {
+	pa, okA := versionParts(a)
+	pb, okB := versionParts(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return 1
+	case !okB:
+		return -1
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// CrossesMajor reports whether moving between a and b changes the major
+// version. Non-numeric versions never do.
+func CrossesMajor(a, b string) bool {
+	pa, okA := versionParts(a)
+	pb, okB := versionParts(b)
+	return okA && okB && pa[0] != pb[0]
+}
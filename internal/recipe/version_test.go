@@ -0,0 +1,50 @@
+package recipe
+
+import "testing"
+
+func TestParseRef(t *testing.T) {
+	cases := map[string][2]string{
+		"ghost":        {"ghost", ""},
+		"ghost@6.18.0": {"ghost", "6.18.0"},
+		"n8n@":         {"n8n", ""},
+	}
+	for ref, want := range cases {
+		name, version := ParseRef(ref)
+		if name != want[0] || version != want[1] {
+			t.Fatalf("ParseRef(%q) = %q, %q; want %q, %q", ref, name, version, want[0], want[1])
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"6.18.0", "6.19.2", -1},
+		{"6.19.2", "6.19.2", 0},
+		{"2.12.2", "2.9.0", 1},
+		{"v2.1.4", "2.1.4", 0},
+		{"2.1", "2.1.0", 0},
+		{"16-alpine", "15.4", 1},
+		{"latest", "1.0.0", 1},
+		{"latest", "latest", 0},
+	}
+	for _, tc := range cases {
+		if got := CompareVersions(tc.a, tc.b); got != tc.want {
+			t.Fatalf("CompareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestCrossesMajor(t *testing.T) {
+	if !CrossesMajor("5.82.2", "6.19.2") {
+		t.Fatal("expected 5.x → 6.x to cross a major version")
+	}
+	if CrossesMajor("6.18.0", "6.19.2") {
+		t.Fatal("expected 6.18 → 6.19 not to cross a major version")
+	}
+	if CrossesMajor("latest", "2.0.0") {
+		t.Fatal("expected non-numeric versions never to cross")
+	}
+}
@@ -0,0 +1,281 @@
+// Package reconcile rebuilds bunkr's state from what is actually on the
+// server: app directories under /opt/bunkr, the Caddyfile's bunkr blocks,
+// Tailscale Serve, ufw rules and the hardening steps' own checks.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/hardening"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+	"github.com/pankajbeniwal/bunkr/internal/tailscale"
+	"gopkg.in/yaml.v3"
+)
+
+const appsDir = "/opt/bunkr"
+
+// Lookup fetches the recipe an app was installed from. It names endpoints
+// and tells private apps from public ones; without it both are guessed.
+type Lookup func(name, version string) (*recipe.Recipe, error)
+
+// Result is the reconstructed state, with notes on anything that had to be
+// guessed or didn't line up.
+type Result struct {
+	State *state.State
+	Notes []string
+}
+
+func (r *Result) note(format string, args ...any) {
+	r.Notes = append(r.Notes, fmt.Sprintf(format, args...))
+}
+
+// Scan inspects the server read-only and returns the state bunkr would have
+// written for what it finds.
+func Scan(ctx context.Context, exec executor.Executor, lookup Lookup) (*Result, error) {
+	res := &Result{State: state.New()}
+	s := res.State
+
+	s.Hardening = hardening.Detect(ctx, exec)
+
+	if _, err := exec.Run(ctx, "which tailscale"); err == nil {
+		s.Tailscale.Installed = true
+		s.Tailscale.Connected, _ = tailscale.IsConnected(ctx, exec)
+		if s.Tailscale.Connected {
+			s.Tailscale.Hostname, _ = tailscale.Hostname(ctx, exec)
+		}
+	}
+
+	sites, err := caddy.AppSites(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+	served, err := tailscale.Served(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := hardening.AllowedPorts(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Run(ctx, fmt.Sprintf("ls -1 %s 2>/dev/null || true", appsDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", appsDir, err)
+	}
+	for _, name := range strings.Fields(out) {
+		dir := appsDir + "/" + name
+		data, err := exec.ReadFile(ctx, dir+"/docker-compose.yml")
+		if errors.Is(err, os.ErrNotExist) {
+			res.note("%s: skipped, no docker-compose.yml", dir)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		app, ok := res.scanApp(name, data, sites[name], served, allowed, s.Tailscale.Hostname, lookup)
+		if !ok {
+			continue
+		}
+		if mtime, err := exec.Run(ctx, "stat -c %Y "+dir); err == nil {
+			if sec, err := strconv.ParseInt(strings.TrimSpace(mtime), 10, 64); err == nil {
+				app.InstalledAt = time.Unix(sec, 0).UTC()
+			}
+		}
+		s.Recipes[name] = app
+	}
+
+	var orphans []string
+	for name := range sites {
+		if _, ok := s.Recipes[name]; !ok {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	for _, name := range orphans {
+		res.note("Caddyfile has a block for %s but %s/%s doesn't exist", name, appsDir, name)
+	}
+	return res, nil
+}
+
+type composeFile struct {
+	Services map[string]struct {
+		Image     string   `yaml:"image"`
+		Ports     []string `yaml:"ports"`
+		MemLimit  string   `yaml:"mem_limit"`
+		CPUs      float64  `yaml:"cpus"`
+		PidsLimit int      `yaml:"pids_limit"`
+		Restart   string   `yaml:"restart"`
+	} `yaml:"services"`
+}
+
+// published is one "ports:" entry of a compose service.
+type published struct {
+	host, container int
+	loopback        bool
+}
+
+func (res *Result) scanApp(name string, data []byte, sites []caddy.Site, served *tailscale.ServeConfig, allowed map[string]bool, tsHostname string, lookup Lookup) (state.RecipeState, bool) {
+	var cf composeFile
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		res.note("%s: skipped, can't parse docker-compose.yml: %v", name, err)
+		return state.RecipeState{}, false
+	}
+	svc, ok := cf.Services[name]
+	if !ok {
+		res.note("%s: skipped, docker-compose.yml has no %s service", name, name)
+		return state.RecipeState{}, false
+	}
+
+	app := state.RecipeState{Version: recipe.ImageVersion(svc.Image)}
+	var ports []published
+	for _, p := range svc.Ports {
+		if pp, ok := parsePort(p); ok {
+			ports = append(ports, pp)
+		}
+	}
+
+	r, err := lookup(name, app.Version)
+	if err != nil {
+		r = nil
+		res.note("%s: recipe %s@%s not found, endpoint names and access are guessed", name, name, app.Version)
+	}
+
+	// Caddy routes by host port
+	type route struct {
+		domain, path string
+		strip        bool
+	}
+	routes := make(map[int]route)
+	for _, site := range sites {
+		for _, rt := range site.Routes {
+			routes[rt.Port] = route{site.Domain, rt.Path, rt.StripPath}
+		}
+	}
+
+	if r != nil {
+		app.Private = r.Private
+	} else {
+		app.Private = len(sites) == 0 && anyServed(ports, served)
+	}
+
+	var recipeEps []recipe.Endpoint
+	if r != nil {
+		recipeEps = r.EndpointList()
+	}
+	for i, p := range ports {
+		ep := state.EndpointState{Port: p.host, ContainerPort: p.container, Protocol: recipe.ProtocolHTTP}
+		if re, ok := matchEndpoint(recipeEps, len(ports), i, p.container); ok {
+			ep.Name, ep.Protocol, ep.Path, ep.StripPath = re.Name, re.Protocol, re.Path, re.StripPath
+		} else {
+			ep.Name = "web"
+			if i > 0 {
+				ep.Name = fmt.Sprintf("port-%d", p.container)
+			}
+			if !p.loopback || served.TCP[p.host] {
+				ep.Protocol = recipe.ProtocolTCP
+			}
+			if rt, ok := routes[p.host]; ok {
+				ep.Path, ep.StripPath = rt.path, rt.strip
+			} else if path, ok := served.HTTP[p.host]; ok && app.Private {
+				ep.Path = path
+			}
+		}
+
+		switch {
+		case app.Private && ep.Protocol == recipe.ProtocolTCP:
+			if !served.TCP[p.host] {
+				res.note("%s: Tailscale Serve doesn't forward tcp port %d", name, p.host)
+			}
+		case app.Private:
+			ep.Domain = tsHostname
+			if _, ok := served.HTTP[p.host]; !ok {
+				res.note("%s: Tailscale Serve doesn't proxy port %d", name, p.host)
+			}
+		case ep.Protocol == recipe.ProtocolTCP:
+			if !allowed[fmt.Sprintf("%d/tcp", p.host)] {
+				res.note("%s: ufw doesn't allow %d/tcp", name, p.host)
+			}
+		default:
+			if rt, ok := routes[p.host]; ok {
+				ep.Domain = rt.domain
+			} else {
+				res.note("%s: no Caddy site routes to port %d", name, p.host)
+			}
+		}
+		app.Endpoints = append(app.Endpoints, ep)
+	}
+	if len(app.Endpoints) > 0 {
+		app.Domain = app.Endpoints[0].Domain
+		app.Port = app.Endpoints[0].Port
+		app.ContainerPort = app.Endpoints[0].ContainerPort
+	}
+
+	// Only install-time overrides are kept in state, so compare with the
+	// recipe's own defaults when we have it
+	limits := &state.ResourceLimits{Memory: svc.MemLimit, CPUs: svc.CPUs, Pids: svc.PidsLimit}
+	if r != nil && r.Resources.Memory == limits.Memory && r.Resources.CPUs == limits.CPUs && r.Resources.Pids == limits.Pids {
+		limits = nil
+	}
+	if limits != nil && *limits != (state.ResourceLimits{}) {
+		app.Limits = limits
+	}
+	if svc.Restart != "" && svc.Restart != "unless-stopped" && (r == nil || svc.Restart != r.Restart) {
+		app.Restart = svc.Restart
+	}
+	return app, true
+}
+
+// matchEndpoint pairs the i-th published port with the recipe endpoint it
+// was generated from: by position when the counts match (GenerateCompose
+// publishes them in EndpointList order), otherwise by container port.
+func matchEndpoint(eps []recipe.Endpoint, published, i, containerPort int) (recipe.Endpoint, bool) {
+	if len(eps) == published {
+		return eps[i], true
+	}
+	for _, ep := range eps {
+		if ep.Port == containerPort {
+			return ep, true
+		}
+	}
+	return recipe.Endpoint{}, false
+}
+
+func anyServed(ports []published, served *tailscale.ServeConfig) bool {
+	for _, p := range ports {
+		if _, ok := served.HTTP[p.host]; ok || served.TCP[p.host] {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePort reads a compose short-syntax port: "127.0.0.1:8080:80",
+// "8080:80" or either with a "/tcp" suffix.
+func parsePort(s string) (published, bool) {
+	s = strings.TrimSuffix(s, "/tcp")
+	parts := strings.Split(s, ":")
+	var p published
+	switch len(parts) {
+	case 2:
+	case 3:
+		p.loopback = parts[0] == "127.0.0.1" || parts[0] == "localhost"
+		parts = parts[1:]
+	default:
+		return p, false
+	}
+	var err1, err2 error
+	p.host, err1 = strconv.Atoi(parts[0])
+	p.container, err2 = strconv.Atoi(parts[1])
+	return p, err1 == nil && err2 == nil
+}
@@ -0,0 +1,161 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+)
+
+func ghostRecipe() *recipe.Recipe {
+	return &recipe.Recipe{
+		Name:    "ghost",
+		Version: "6.19.2",
+		Image:   "ghost:6.19.2",
+		Prompts: []recipe.Prompt{{Key: "DOMAIN"}},
+		Endpoints: []recipe.Endpoint{
+			{Name: "web", Port: 2368, DomainPrompt: "DOMAIN"},
+			{Name: "admin", Port: 2369, Path: "/ghost/", StripPath: true},
+			{Name: "smtp", Port: 25, Protocol: recipe.ProtocolTCP, HostPort: 2525},
+		},
+		Resources: recipe.Resources{Memory: "512m"},
+	}
+}
+
+func lookupOnly(recipes ...*recipe.Recipe) Lookup {
+	return func(name, version string) (*recipe.Recipe, error) {
+		for _, r := range recipes {
+			if r.Name == name && r.Version == version {
+				return r, nil
+			}
+		}
+		return nil, fmt.Errorf("recipe %s@%s not found", name, version)
+	}
+}
+
+func newServer(t *testing.T) *executor.MockExecutor {
+	t.Helper()
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+
+	// ghost: public, installed from a known recipe with a memory override
+	ghost := ghostRecipe()
+	ghost.Resources.Memory = "1g"
+	compose, err := recipe.GenerateCompose(ghost, nil, 2370, 2371, 2525)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.Files["/opt/bunkr/ghost/docker-compose.yml"] = compose
+	if err := caddy.AddSites(ctx, mock, "ghost", []caddy.Site{{Domain: "blog.example.com", Routes: []caddy.Route{
+		{Path: "/ghost", StripPath: true, Port: 2371},
+		{Port: 2370},
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+	mock.RunOutputs["ufw status 2>/dev/null || true"] = "Status: active\n\n2525/tcp                   ALLOW       Anywhere\n"
+
+	// tool: private, recipe no longer published
+	mock.Files["/opt/bunkr/tool/docker-compose.yml"] = []byte(`services:
+  tool:
+    image: ghcr.io/acme/tool:1.4.0
+    ports:
+      - 127.0.0.1:18789:18789
+    restart: always
+`)
+	mock.RunOutputs["tailscale serve status --json 2>/dev/null || true"] = `{"Web": {"box.tail1234.ts.net:443": {"Handlers": {"/": {"Proxy": "http://localhost:18789"}}}}}`
+	mock.RunOutputs["tailscale status --json 2>/dev/null || true"] = `{"BackendState": "Running", "Self": {"DNSName": "box.tail1234.ts.net."}}`
+
+	// Leftovers
+	mock.Files[caddy.CaddyfilePath] = append(mock.Files[caddy.CaddyfilePath], []byte("\n# bunkr:gone\ngone.example.com {\n    reverse_proxy localhost:4000\n}\n# /bunkr:gone\n")...)
+	mock.RunOutputs["ls -1 /opt/bunkr 2>/dev/null || true"] = "ghost\nscratch\ntool\n"
+	mock.RunOutputs["stat -c %Y /opt/bunkr/ghost"] = "1767225600\n"
+	mock.Files["/etc/ssh/sshd_config.d/99-bunkr.conf"] = []byte("Port 2222\n")
+	return mock
+}
+
+func TestScan(t *testing.T) {
+	mock := newServer(t)
+
+	res, err := Scan(context.Background(), mock, lookupOnly(ghostRecipe()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := res.State
+
+	if !s.Hardening.Applied || s.Hardening.SSHPort != 2222 {
+		t.Errorf("expected hardening detected on port 2222, got %+v", s.Hardening)
+	}
+	if !s.Tailscale.Connected || s.Tailscale.Hostname != "box.tail1234.ts.net" {
+		t.Errorf("unexpected tailscale state: %+v", s.Tailscale)
+	}
+	if len(s.Recipes) != 2 {
+		t.Fatalf("expected ghost and tool, got %v", s.Recipes)
+	}
+
+	ghost := s.Recipes["ghost"]
+	want := []state.EndpointState{
+		{Name: "web", Protocol: "http", Domain: "blog.example.com", Port: 2370, ContainerPort: 2368},
+		{Name: "admin", Protocol: "http", Domain: "blog.example.com", Path: "/ghost/", StripPath: true, Port: 2371, ContainerPort: 2369},
+		{Name: "smtp", Protocol: "tcp", Port: 2525, ContainerPort: 25},
+	}
+	if ghost.Version != "6.19.2" || ghost.Private || ghost.Domain != "blog.example.com" || ghost.Port != 2370 {
+		t.Errorf("unexpected ghost state: %+v", ghost)
+	}
+	if len(ghost.Endpoints) != len(want) {
+		t.Fatalf("expected %d endpoints, got %+v", len(want), ghost.Endpoints)
+	}
+	for i := range want {
+		if ghost.Endpoints[i] != want[i] {
+			t.Errorf("endpoint %d: got %+v, want %+v", i, ghost.Endpoints[i], want[i])
+		}
+	}
+	if ghost.Limits == nil || ghost.Limits.Memory != "1g" {
+		t.Errorf("expected the memory override to be kept, got %+v", ghost.Limits)
+	}
+	if ghost.InstalledAt.Unix() != 1767225600 {
+		t.Errorf("expected install time from the app directory, got %v", ghost.InstalledAt)
+	}
+
+	tool := s.Recipes["tool"]
+	if tool.Version != "1.4.0" || !tool.Private || tool.Restart != "always" {
+		t.Errorf("unexpected tool state: %+v", tool)
+	}
+	if len(tool.Endpoints) != 1 || tool.Endpoints[0].Domain != "box.tail1234.ts.net" || tool.Endpoints[0].Port != 18789 {
+		t.Errorf("unexpected tool endpoints: %+v", tool.Endpoints)
+	}
+
+	notes := strings.Join(res.Notes, "\n")
+	for _, want := range []string{
+		"/opt/bunkr/scratch: skipped",
+		"tool: recipe tool@1.4.0 not found",
+		"block for gone",
+	} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("expected a note containing %q, got:\n%s", want, notes)
+		}
+	}
+}
+
+func TestScan_Mismatches(t *testing.T) {
+	mock := newServer(t)
+	mock.RunOutputs["ufw status 2>/dev/null || true"] = "Status: active\n"
+	mock.RunOutputs["tailscale serve status --json 2>/dev/null || true"] = "{}"
+
+	res, err := Scan(context.Background(), mock, lookupOnly(ghostRecipe()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	notes := strings.Join(res.Notes, "\n")
+	if !strings.Contains(notes, "ghost: ufw doesn't allow 2525/tcp") {
+		t.Errorf("expected a missing firewall rule note, got:\n%s", notes)
+	}
+	// Without Serve config or a Caddy site, tool looks public and unrouted
+	if !strings.Contains(notes, "tool: no Caddy site routes to port 18789") {
+		t.Errorf("expected a missing route note, got:\n%s", notes)
+	}
+}
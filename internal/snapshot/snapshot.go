@@ -0,0 +1,168 @@
+// Package snapshot keeps copies of the files bunkr rewrites (state.json, the
+// Caddyfile and each app's directory of compose, .env and config files) so a
+// bad change can be rolled back.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankajbeniwal/bunkr/internal/caddy"
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/state"
+)
+
+const (
+	Dir = "/etc/bunkr/snapshots"
+
+	// Keep is how many snapshots Prune leaves behind.
+	Keep = 10
+
+	metaFile = "snapshot.json"
+)
+
+// Info describes a snapshot and is stored next to its files.
+type Info struct {
+	ID      string    `json:"id"`
+	Command string    `json:"command"`
+	Created time.Time `json:"created"`
+	Apps    []string  `json:"apps,omitempty"`
+}
+
+func path(id string) string {
+	return Dir + "/" + id
+}
+
+func appDir(app string) string {
+	return "/opt/bunkr/" + app
+}
+
+// Take copies the current files into a new snapshot named after the time.
+// Files that don't exist yet (a first install, an app being installed) are
+// skipped; Restore treats them as absent.
+func Take(ctx context.Context, exec executor.Executor, command string, apps []string) (*Info, error) {
+	now := time.Now().UTC()
+	info := &Info{
+		ID:      now.Format("20060102T150405.000Z"),
+		Command: command,
+		Created: now,
+		Apps:    apps,
+	}
+	dir := path(info.ID)
+
+	cmds := []string{
+		"mkdir -p " + dir,
+		copyIfExists(state.StatePath, dir+"/state.json"),
+		copyIfExists(caddy.CaddyfilePath, dir+"/Caddyfile"),
+	}
+	// App directories hold only config (data lives in volumes), so they
+	// are copied whole, including files rendered from recipe templates
+	for _, app := range apps {
+		cmds = append(cmds, "mkdir -p "+dir+"/apps/"+app, copyDirIfExists(appDir(app), dir+"/apps/"+app))
+	}
+	if _, err := exec.Run(ctx, strings.Join(cmds, " && ")); err != nil {
+		return nil, fmt.Errorf("failed to take snapshot: %w", err)
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := exec.WriteFile(ctx, dir+"/"+metaFile, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to take snapshot: %w", err)
+	}
+	return info, nil
+}
+
+// List returns the snapshots on the server, newest first.
+func List(ctx context.Context, exec executor.Executor) ([]Info, error) {
+	out, err := exec.Run(ctx, fmt.Sprintf("ls -1 %s 2>/dev/null || true", Dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var snapshots []Info
+	for _, id := range strings.Fields(out) {
+		data, err := exec.ReadFile(ctx, path(id)+"/"+metaFile)
+		if err != nil {
+			// Interrupted before the metadata was written
+			continue
+		}
+		var info Info
+		if err := json.Unmarshal(data, &info); err != nil {
+			continue
+		}
+		info.ID = id
+		snapshots = append(snapshots, info)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].ID > snapshots[j].ID })
+	return snapshots, nil
+}
+
+// Find returns the snapshot with the given ID, or the newest when id is "".
+func Find(ctx context.Context, exec executor.Executor, id string) (*Info, error) {
+	snapshots, err := List(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshots found in %s", Dir)
+	}
+	if id == "" {
+		return &snapshots[0], nil
+	}
+	for _, s := range snapshots {
+		if s.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot %s not found (run 'bunkr rollback --list')", id)
+}
+
+// Restore copies a snapshot's files back into place. state.json is removed
+// if the snapshot predates it; an app without files in the snapshot is
+// returned in absent so the caller can stop it.
+func Restore(ctx context.Context, exec executor.Executor, info *Info) (absent []string, err error) {
+	dir := path(info.ID)
+
+	cmds := []string{
+		fmt.Sprintf("if [ -f %s/state.json ]; then cp -p %s/state.json %s; else rm -f %s; fi", dir, dir, state.StatePath, state.StatePath),
+		copyIfExists(dir+"/Caddyfile", caddy.CaddyfilePath),
+	}
+	for _, app := range info.Apps {
+		saved := dir + "/apps/" + app
+		if _, err := exec.Run(ctx, fmt.Sprintf("test -f %s/docker-compose.yml", saved)); err != nil {
+			absent = append(absent, app)
+			continue
+		}
+		// uninstall removes the app directory
+		cmds = append(cmds, "mkdir -p "+appDir(app), copyDirIfExists(saved, appDir(app)))
+	}
+	if _, err := exec.Run(ctx, strings.Join(cmds, " && ")); err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot %s: %w", info.ID, err)
+	}
+	return absent, nil
+}
+
+// Prune deletes all but the newest keep snapshots.
+func Prune(ctx context.Context, exec executor.Executor, keep int) error {
+	cmd := fmt.Sprintf("ls -1 %s 2>/dev/null | sort -r | tail -n +%d | while read -r s; do rm -rf %s/\"$s\"; done", Dir, keep+1, Dir)
+	if _, err := exec.Run(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+	return nil
+}
+
+func copyIfExists(src, dst string) string {
+	return fmt.Sprintf("{ [ ! -f %s ] || cp -p %s %s; }", src, src, dst)
+}
+
+// copyDirIfExists copies the contents of src into dst, keeping owners and
+// modes.
+func copyDirIfExists(src, dst string) string {
+	return fmt.Sprintf("{ [ ! -d %s ] || cp -a %s/. %s/; }", src, src, dst)
+}
@@ -0,0 +1,196 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"github.com/pankajbeniwal/bunkr/internal/recipe"
+)
+
+// rootedExecutor runs commands locally with the server paths bunkr uses moved
+// under root, so snapshots can be taken and restored for real.
+type rootedExecutor struct {
+	local *executor.LocalExecutor
+	paths *strings.Replacer
+}
+
+func newRootedExecutor(root string) *rootedExecutor {
+	return &rootedExecutor{
+		local: executor.NewLocalExecutor(),
+		paths: strings.NewReplacer(
+			"/etc/bunkr", root+"/etc/bunkr",
+			"/etc/caddy", root+"/etc/caddy",
+			"/opt/bunkr", root+"/opt/bunkr",
+		),
+	}
+}
+
+func (r *rootedExecutor) Run(ctx context.Context, cmd string) (string, error) {
+	return r.local.Run(ctx, r.paths.Replace(cmd))
+}
+
+func (r *rootedExecutor) WriteFile(ctx context.Context, path string, content []byte, mode os.FileMode) error {
+	return r.local.WriteFile(ctx, r.paths.Replace(path), content, mode)
+}
+
+func (r *rootedExecutor) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	return r.local.ReadFile(ctx, r.paths.Replace(path))
+}
+
+func (r *rootedExecutor) Stream(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+	return r.local.Stream(ctx, r.paths.Replace(cmd), stdin, stdout)
+}
+
+func TestTake(t *testing.T) {
+	mock := executor.NewMockExecutor()
+
+	info, err := Take(context.Background(), mock, "bunkr update ghost", []string{"ghost"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir := Dir + "/" + info.ID
+	cmd := mock.Calls[0].Args[0].(string)
+	for _, want := range []string{
+		"mkdir -p " + dir,
+		"cp -p /etc/bunkr/state.json " + dir + "/state.json",
+		"cp -p /etc/caddy/Caddyfile " + dir + "/Caddyfile",
+		"cp -a /opt/bunkr/ghost/. " + dir + "/apps/ghost/",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected snapshot command to contain %q:\n%s", want, cmd)
+		}
+	}
+	if meta := string(mock.Files[dir+"/snapshot.json"]); !strings.Contains(meta, `"command": "bunkr update ghost"`) {
+		t.Fatalf("expected metadata to be written, got %q", meta)
+	}
+}
+
+func TestListAndFind(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+	mock.RunOutputs["ls -1 "+Dir+" 2>/dev/null || true"] = "20260101T000000.000Z\n20260102T000000.000Z\n20260103T000000.000Z\n"
+	mock.Files[Dir+"/20260101T000000.000Z/snapshot.json"] = []byte(`{"command": "bunkr install ghost", "apps": ["ghost"]}`)
+	mock.Files[Dir+"/20260102T000000.000Z/snapshot.json"] = []byte(`{"command": "bunkr update ghost", "apps": ["ghost"]}`)
+	// 20260103 was interrupted before its metadata was written
+
+	snapshots, err := List(ctx, mock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].ID != "20260102T000000.000Z" {
+		t.Fatalf("expected 2 snapshots newest first, got %+v", snapshots)
+	}
+
+	latest, err := Find(ctx, mock, "")
+	if err != nil || latest.Command != "bunkr update ghost" {
+		t.Fatalf("expected the newest snapshot, got %+v, %v", latest, err)
+	}
+	if _, err := Find(ctx, mock, "nope"); err == nil {
+		t.Fatal("expected an error for an unknown snapshot")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	info := &Info{ID: "20260102T000000.000Z", Apps: []string{"ghost", "n8n"}}
+	dir := Dir + "/" + info.ID
+	// n8n was installed after this snapshot
+	mock.RunErrors["test -f "+dir+"/apps/n8n/docker-compose.yml"] = errors.New("exit status 1")
+
+	absent, err := Restore(context.Background(), mock, info)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(absent) != 1 || absent[0] != "n8n" {
+		t.Fatalf("expected n8n to be absent, got %v", absent)
+	}
+
+	cmd := mock.Calls[len(mock.Calls)-1].Args[0].(string)
+	for _, want := range []string{
+		"cp -p " + dir + "/state.json /etc/bunkr/state.json",
+		"cp -p " + dir + "/Caddyfile /etc/caddy/Caddyfile",
+		"mkdir -p /opt/bunkr/ghost",
+		"cp -a " + dir + "/apps/ghost/. /opt/bunkr/ghost/",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected restore command to contain %q:\n%s", want, cmd)
+		}
+	}
+	if strings.Contains(cmd, "/opt/bunkr/n8n") {
+		t.Errorf("expected n8n's files to be left alone:\n%s", cmd)
+	}
+}
+
+func TestTakeAndRestore_Files(t *testing.T) {
+	ctx := context.Background()
+	exec := newRootedExecutor(t.TempDir())
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "recipes", "openclaw.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := recipe.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Files) == 0 {
+		t.Fatal("expected openclaw to render config files")
+	}
+	install := func(token string) map[string][]byte {
+		values := map[string]string{"OPENCLAW_GATEWAY_TOKEN": token}
+		files, err := recipe.RenderFiles(r, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		written := map[string][]byte{
+			"docker-compose.yml": []byte("services: {}\n"),
+			".env":               recipe.GenerateEnv(values),
+		}
+		for _, f := range files {
+			written[f.Path] = f.Content
+		}
+		for path, content := range written {
+			if err := exec.WriteFile(ctx, "/opt/bunkr/openclaw/"+path, content, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return written
+	}
+
+	before := install("old-token")
+	info, err := Take(ctx, exec, "bunkr update openclaw", []string{"openclaw"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	install("new-token")
+
+	if _, err := Restore(ctx, exec, info); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for path, want := range before {
+		got, err := exec.ReadFile(ctx, "/opt/bunkr/openclaw/"+path)
+		if err != nil {
+			t.Fatalf("expected %s to be restored: %v", path, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("expected %s to be restored to\n%s\ngot\n%s", path, want, got)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	if err := Prune(context.Background(), mock, 3); err != nil {
+		t.Fatal(err)
+	}
+	if cmd := mock.Calls[0].Args[0].(string); !strings.Contains(cmd, "sort -r | tail -n +4") {
+		t.Fatalf("expected all but the newest 3 to be removed, got %s", cmd)
+	}
+}
@@ -0,0 +1,52 @@
+package state
+
+import "time"
+
+// Event kinds.
+const (
+	EventInstalled    = "installed"
+	EventUpdated      = "updated"
+	EventReconfigured = "reconfigured"
+	EventUninstalled  = "uninstalled"
+	EventRolledBack   = "rolled_back"
+	EventHardened     = "hardened"
+	EventAdopted      = "adopted"
+	EventMigrated     = "migrated"
+	EventFailed       = "failed"
+)
+
+// Event is one entry in the history. App is empty for server-wide events
+// such as hardening; By is the user@host that ran Command.
+type Event struct {
+	Time    time.Time `json:"time"`
+	App     string    `json:"app,omitempty"`
+	Kind    string    `json:"kind"`
+	Detail  string    `json:"detail,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	By      string    `json:"by,omitempty"`
+	Command string    `json:"command,omitempty"`
+}
+
+// Record appends e to the history, stamping it with the current time if it
+// has none.
+func (s *State) Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	s.Events = append(s.Events, e)
+}
+
+// History returns app's events, oldest first. An empty app returns every
+// event, server-wide ones included.
+func (s *State) History(app string) []Event {
+	if app == "" {
+		return s.Events
+	}
+	var events []Event
+	for _, e := range s.Events {
+		if e.App == app {
+			events = append(events, e)
+		}
+	}
+	return events
+}
@@ -0,0 +1,64 @@
+package state
+
+import "fmt"
+
+// SchemaVersion is the state file layout this build reads and writes.
+// Bump it together with a new entry in migrations.
+const SchemaVersion = 1
+
+// migrations[i] upgrades a decoded state file from schema version i to i+1.
+// They work on the raw JSON so fields can be renamed or moved without the
+// old layout having to stay in the structs.
+var migrations = []func(doc map[string]any) error{
+	migrateEndpoints,
+}
+
+// migrate upgrades a decoded state file to SchemaVersion in place and
+// reports whether anything changed. Files written before schema_version
+// existed are version 0.
+func migrate(doc map[string]any) (bool, error) {
+	version := 0
+	if v, ok := doc["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	if version > SchemaVersion {
+		return false, fmt.Errorf("state file is schema version %d but this bunkr only knows up to %d, run 'bunkr self-update'", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return false, nil
+	}
+
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return false, fmt.Errorf("%w: migrating from schema version %d: %v", ErrCorrupt, version, err)
+		}
+	}
+	doc["schema_version"] = SchemaVersion
+	return true, nil
+}
+
+// migrateEndpoints (0 → 1) gives apps installed before endpoints were
+// tracked an explicit web endpoint built from their port and domain.
+func migrateEndpoints(doc map[string]any) error {
+	recipes, _ := doc["recipes"].(map[string]any)
+	for name, v := range recipes {
+		app, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("recipe %s: unexpected %T", name, v)
+		}
+		if eps, ok := app["endpoints"].([]any); ok && len(eps) > 0 {
+			continue
+		}
+		ep := map[string]any{
+			"name":           "web",
+			"protocol":       "http",
+			"port":           app["port"],
+			"container_port": app["container_port"],
+		}
+		if domain, _ := app["domain"].(string); domain != "" {
+			ep["domain"] = domain
+		}
+		app["endpoints"] = []any{ep}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pankajbeniwal/bunkr/internal/executor"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	appsDir      = "/opt/bunkr"
+	listeningCmd = "ss -Hltun"
+)
+
+// ErrNoSocketList is returned by ScanPorts when the host has no ss to list
+// listening sockets. The compose files were still scanned, so ports can be
+// allocated, but one taken by something outside bunkr may be picked.
+var ErrNoSocketList = errors.New("ss is not installed, so ports used outside bunkr's apps can't be seen")
+
+// ScanPorts records host ports that are taken outside state: listening tcp
+// and udp sockets, and ports published by any compose file under
+// /opt/bunkr, running or not. AllocatePort skips them afterwards.
+func (s *State) ScanPorts(ctx context.Context, exec executor.Executor) error {
+	if s.inUse == nil {
+		s.inUse = make(map[int]bool)
+	}
+
+	sockets := true
+	out, err := exec.Run(ctx, listeningCmd)
+	if err != nil {
+		if _, lookErr := exec.Run(ctx, "command -v ss"); lookErr == nil {
+			return fmt.Errorf("failed to list listening ports: %w", err)
+		}
+		sockets, out = false, ""
+	}
+	for _, line := range strings.Split(out, "\n") {
+		// Netid State Recv-Q Send-Q Local:Port Peer:Port
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		local := fields[4]
+		if port, err := strconv.Atoi(local[strings.LastIndex(local, ":")+1:]); err == nil {
+			s.inUse[port] = true
+		}
+	}
+
+	out, err = exec.Run(ctx, fmt.Sprintf("ls -1 %s 2>/dev/null || true", appsDir))
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", appsDir, err)
+	}
+	for _, name := range strings.Fields(out) {
+		path := fmt.Sprintf("%s/%s/docker-compose.yml", appsDir, name)
+		data, err := exec.ReadFile(ctx, path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var cf struct {
+			Services map[string]struct {
+				Ports []yaml.Node `yaml:"ports"`
+			} `yaml:"services"`
+		}
+		if err := yaml.Unmarshal(data, &cf); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for _, svc := range cf.Services {
+			for _, p := range svc.Ports {
+				for _, port := range publishedPorts(p) {
+					s.inUse[port] = true
+				}
+			}
+		}
+	}
+	if !sockets {
+		return ErrNoSocketList
+	}
+	return nil
+}
+
+// publishedPorts returns the host ports of one compose "ports:" entry, in
+// short ("127.0.0.1:8080:80/tcp", "9000-9002:9000-9002") or long syntax.
+// Entries that only name a container port publish nothing fixed.
+func publishedPorts(n yaml.Node) []int {
+	var host string
+	switch n.Kind {
+	case yaml.ScalarNode:
+		spec, _, _ := strings.Cut(n.Value, "/")
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 {
+			return nil
+		}
+		host = parts[len(parts)-2]
+	case yaml.MappingNode:
+		var long struct {
+			Published string `yaml:"published"`
+		}
+		if err := n.Decode(&long); err != nil {
+			return nil
+		}
+		host = long.Published
+	}
+
+	lo, hi, isRange := strings.Cut(host, "-")
+	first, err := strconv.Atoi(lo)
+	if err != nil {
+		return nil
+	}
+	last := first
+	if isRange {
+		if last, err = strconv.Atoi(hi); err != nil || last < first {
+			return nil
+		}
+	}
+	var ports []int
+	for p := first; p <= last; p++ {
+		ports = append(ports, p)
+	}
+	return ports
+}
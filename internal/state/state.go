@@ -3,6 +3,9 @@ package state
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/pankajbeniwal/bunkr/internal/executor"
@@ -10,10 +13,23 @@ import (
 
 const StatePath = "/etc/bunkr/state.json"
 
+// ErrCorrupt is returned by Load when the state file exists but can't be
+// decoded. Callers must not fall back to an empty state on their own.
+var ErrCorrupt = errors.New("state file is corrupt")
+
 type State struct {
-	Hardening HardeningState         `json:"hardening"`
-	Tailscale TailscaleState         `json:"tailscale"`
-	Recipes   map[string]RecipeState `json:"recipes"`
+	SchemaVersion int                    `json:"schema_version"`
+	Hardening     HardeningState         `json:"hardening"`
+	Tailscale     TailscaleState         `json:"tailscale"`
+	Recipes       map[string]RecipeState `json:"recipes"`
+
+	// Append-only log of changes to the server and its apps, oldest first
+	Events []Event `json:"events,omitempty"`
+
+	// ports handed out by AllocatePort that aren't in Recipes yet
+	reserved map[int]bool
+	// ports taken on the host outside Recipes, filled by ScanPorts
+	inUse map[int]bool
 }
 
 type TailscaleState struct {
@@ -36,10 +52,51 @@ type RecipeState struct {
 	InstalledAt   time.Time `json:"installed_at"`
 	Port          int       `json:"port"`
 	ContainerPort int       `json:"container_port"`
+
+	// Every exposed endpoint. Port, Domain and ContainerPort above mirror
+	// the first one; entries written before endpoints existed have none.
+	Endpoints []EndpointState `json:"endpoints,omitempty"`
+
+	// Install-time overrides for the primary service, re-applied on update.
+	Limits   *ResourceLimits   `json:"limits,omitempty"`
+	Restart  string            `json:"restart,omitempty"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+type EndpointState struct {
+	Name          string `json:"name"`
+	Protocol      string `json:"protocol"`
+	Domain        string `json:"domain,omitempty"`
+	Path          string `json:"path,omitempty"`
+	StripPath     bool   `json:"strip_path,omitempty"`
+	Port          int    `json:"port"`
+	ContainerPort int    `json:"container_port"`
+}
+
+// EndpointList returns the app's endpoints, synthesizing a single http
+// endpoint for state written before endpoints were tracked.
+func (r RecipeState) EndpointList() []EndpointState {
+	if len(r.Endpoints) > 0 {
+		return r.Endpoints
+	}
+	return []EndpointState{{
+		Name:          "web",
+		Protocol:      "http",
+		Domain:        r.Domain,
+		Port:          r.Port,
+		ContainerPort: r.ContainerPort,
+	}}
+}
+
+type ResourceLimits struct {
+	Memory string  `json:"memory,omitempty"`
+	CPUs   float64 `json:"cpus,omitempty"`
+	Pids   int     `json:"pids,omitempty"`
 }
 
 func New() *State {
 	return &State{
+		SchemaVersion: SchemaVersion,
 		Hardening: HardeningState{
 			Steps: make(map[string]bool),
 		},
@@ -47,14 +104,42 @@ func New() *State {
 	}
 }
 
+// Load reads the state file, migrating it from older schema versions. A
+// missing file is a fresh server; any other read error is returned rather
+// than treated as empty, so a flaky connection can't make bunkr re-harden or
+// hand out ports that are already in use.
 func Load(ctx context.Context, exec executor.Executor) (*State, error) {
 	data, err := exec.ReadFile(ctx, StatePath)
-	if err != nil {
+	if errors.Is(err, os.ErrNotExist) {
 		return New(), nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return decode(data)
+}
+
+func decode(data []byte) (*State, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("%w: not a JSON object", ErrCorrupt)
+	}
+	migrated, err := migrate(doc)
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
 	s := New()
 	if err := json.Unmarshal(data, s); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
 	}
 	if s.Recipes == nil {
 		s.Recipes = make(map[string]RecipeState)
@@ -66,6 +151,7 @@ func Load(ctx context.Context, exec executor.Executor) (*State, error) {
 }
 
 func Save(ctx context.Context, exec executor.Executor, s *State) error {
+	s.SchemaVersion = SchemaVersion
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
 		return err
@@ -73,14 +159,33 @@ func Save(ctx context.Context, exec executor.Executor, s *State) error {
 	return exec.WriteFile(ctx, StatePath, data, 0644)
 }
 
+// Reset moves an unreadable state file aside to <path>.corrupt-<timestamp>
+// and returns a fresh state. The backup path is returned for the user.
+func Reset(ctx context.Context, exec executor.Executor) (*State, string, error) {
+	backup := fmt.Sprintf("%s.corrupt-%s", StatePath, time.Now().UTC().Format("20060102T150405Z"))
+	if _, err := exec.Run(ctx, fmt.Sprintf("mv %s %s", StatePath, backup)); err != nil {
+		return nil, "", fmt.Errorf("failed to move corrupt state aside: %w", err)
+	}
+	return New(), backup, nil
+}
+
+// AllocatePort returns the first free port at or above desired, skipping
+// ports in Recipes and, after ScanPorts, ports in use on the host. The port
+// is reserved so later calls in the same run don't hand it out again.
 func (s *State) AllocatePort(desired int) int {
 	taken := make(map[int]bool)
 	for _, r := range s.Recipes {
-		taken[r.Port] = true
+		for _, ep := range r.EndpointList() {
+			taken[ep.Port] = true
+		}
 	}
 	port := desired
-	for taken[port] {
+	for taken[port] || s.reserved[port] || s.inUse[port] {
 		port++
 	}
+	if s.reserved == nil {
+		s.reserved = make(map[int]bool)
+	}
+	s.reserved[port] = true
 	return port
 }
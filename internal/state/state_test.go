@@ -2,6 +2,9 @@ package state
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -80,3 +83,218 @@ func TestAllocatePort(t *testing.T) {
 		t.Fatalf("expected 8080 (free), got %d", port2)
 	}
 }
+
+func TestAllocatePort_Endpoints(t *testing.T) {
+	s := New()
+	s.Recipes["gitea"] = RecipeState{
+		Port: 3000,
+		Endpoints: []EndpointState{
+			{Name: "web", Port: 3000},
+			{Name: "ssh", Port: 2223},
+		},
+	}
+
+	if port := s.AllocatePort(2223); port != 2224 {
+		t.Fatalf("expected 2224 (endpoint port taken), got %d", port)
+	}
+	// The port handed out above is reserved for this run
+	if port := s.AllocatePort(2224); port != 2225 {
+		t.Fatalf("expected 2225 (2224 reserved), got %d", port)
+	}
+}
+
+func TestAllocatePort_HostPorts(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunOutputs["ss -Hltun"] = `tcp   LISTEN 0 4096 127.0.0.1:3000 0.0.0.0:*
+tcp   LISTEN 0 4096      [::]:9100    [::]:*
+udp   UNCONN 0 0      0.0.0.0%lo:3002 0.0.0.0:*
+`
+	mock.RunOutputs["ls -1 /opt/bunkr 2>/dev/null || true"] = "manual\nnotes\n"
+	mock.Files["/opt/bunkr/manual/docker-compose.yml"] = []byte(`services:
+  web:
+    ports:
+      - "127.0.0.1:3001:80"
+      - "3003-3004:3003-3004/udp"
+      - "8080"
+  admin:
+    ports:
+      - target: 80
+        published: "3005"
+`)
+
+	s := New()
+	if err := s.ScanPorts(context.Background(), mock); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if port := s.AllocatePort(3000); port != 3006 {
+		t.Fatalf("expected 3006 (3000-3005 in use on the host), got %d", port)
+	}
+	if port := s.AllocatePort(9100); port != 9101 {
+		t.Fatalf("expected 9101 (node exporter on 9100), got %d", port)
+	}
+	if port := s.AllocatePort(8080); port != 8080 {
+		t.Fatalf("expected 8080 (only a container port), got %d", port)
+	}
+}
+
+func TestScanPorts_Error(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunErrors["ss -Hltun"] = fmt.Errorf("exit status 1")
+	if err := New().ScanPorts(context.Background(), mock); err == nil || errors.Is(err, ErrNoSocketList) {
+		t.Fatalf("expected an error when listening ports can't be listed, got %v", err)
+	}
+}
+
+func TestScanPorts_NoSS(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunErrors["ss -Hltun"] = fmt.Errorf("command not found")
+	mock.RunErrors["command -v ss"] = fmt.Errorf("exit status 127")
+	mock.RunOutputs["ls -1 /opt/bunkr 2>/dev/null || true"] = "ghost\n"
+	mock.Files["/opt/bunkr/ghost/docker-compose.yml"] = []byte("services:\n  ghost:\n    ports:\n      - \"127.0.0.1:3000:2368\"\n")
+
+	s := New()
+	if err := s.ScanPorts(context.Background(), mock); !errors.Is(err, ErrNoSocketList) {
+		t.Fatalf("expected ErrNoSocketList, got %v", err)
+	}
+	if port := s.AllocatePort(3000); port != 3001 {
+		t.Fatalf("expected compose ports to still be scanned, got %d", port)
+	}
+}
+
+func TestRecipeState_EndpointList_Legacy(t *testing.T) {
+	r := RecipeState{Domain: "blog.example.com", Port: 2368, ContainerPort: 2368}
+	eps := r.EndpointList()
+	if len(eps) != 1 || eps[0].Domain != "blog.example.com" || eps[0].Port != 2368 || eps[0].Protocol != "http" {
+		t.Fatalf("unexpected legacy endpoints: %+v", eps)
+	}
+}
+
+func TestLoadState_ReadError(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.ReadErrors[StatePath] = errors.New("connection reset")
+
+	if _, err := Load(context.Background(), mock); err == nil {
+		t.Fatal("expected a failed read to be an error, not an empty state")
+	}
+}
+
+func TestLoadState_Corrupt(t *testing.T) {
+	for name, data := range map[string]string{
+		"truncated":  `{"recipes": {"ghost": {"version": "5.`,
+		"null":       `null`,
+		"array":      `[]`,
+		"wrong type": `{"recipes": []}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mock := executor.NewMockExecutor()
+			mock.Files[StatePath] = []byte(data)
+
+			_, err := Load(context.Background(), mock)
+			if !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("expected ErrCorrupt, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadState_NewerSchema(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.Files[StatePath] = []byte(`{"schema_version": 99}`)
+
+	_, err := Load(context.Background(), mock)
+	if err == nil || errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected a schema version error, got %v", err)
+	}
+}
+
+func TestLoadState_MigratesV0(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.Files[StatePath] = []byte(`{
+		"hardening": {"applied": true, "ssh_port": 2222},
+		"recipes": {
+			"ghost": {"version": "5.82.2", "domain": "blog.example.com", "port": 2368, "container_port": 2368},
+			"n8n": {"version": "2.12.2", "port": 5678, "container_port": 5678,
+				"endpoints": [{"name": "web", "protocol": "http", "domain": "n8n.example.com", "port": 5678, "container_port": 5678}]}
+		}
+	}`)
+
+	s, err := Load(context.Background(), mock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.SchemaVersion != SchemaVersion {
+		t.Fatalf("expected schema version %d, got %d", SchemaVersion, s.SchemaVersion)
+	}
+	if !s.Hardening.Applied || s.Hardening.SSHPort != 2222 {
+		t.Fatalf("expected hardening to survive migration, got %+v", s.Hardening)
+	}
+
+	want := EndpointState{Name: "web", Protocol: "http", Domain: "blog.example.com", Port: 2368, ContainerPort: 2368}
+	if eps := s.Recipes["ghost"].Endpoints; len(eps) != 1 || eps[0] != want {
+		t.Fatalf("expected migrated endpoint %+v, got %+v", want, eps)
+	}
+	if eps := s.Recipes["n8n"].Endpoints; len(eps) != 1 || eps[0].Domain != "n8n.example.com" {
+		t.Fatalf("expected existing endpoints to be kept, got %+v", eps)
+	}
+}
+
+func TestSaveState_WritesSchemaVersion(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	s := New()
+	s.SchemaVersion = 0
+	if err := Save(context.Background(), mock, s); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(mock.Files[StatePath]), fmt.Sprintf(`"schema_version": %d`, SchemaVersion)) {
+		t.Fatalf("expected schema_version in saved state:\n%s", mock.Files[StatePath])
+	}
+}
+
+func TestResetState(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	s, backup, err := Reset(context.Background(), mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Recipes) != 0 {
+		t.Fatal("expected an empty state")
+	}
+	if !strings.HasPrefix(backup, StatePath+".corrupt-") {
+		t.Fatalf("unexpected backup path %s", backup)
+	}
+	if len(mock.Calls) != 1 || mock.Calls[0].Args[0] != "mv "+StatePath+" "+backup {
+		t.Fatalf("expected the state file to be moved aside, got %+v", mock.Calls)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+
+	s := New()
+	s.Record(Event{Kind: EventHardened, Detail: "firewall"})
+	s.Record(Event{App: "ghost", Kind: EventInstalled, Detail: "version 6.18.0", By: "alice@laptop"})
+	s.Record(Event{App: "ghost", Kind: EventFailed, Error: "failed to pull images"})
+	s.Record(Event{App: "plausible", Kind: EventInstalled})
+	if err := Save(ctx, mock, s); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(ctx, mock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded.History("")) != 4 {
+		t.Fatalf("expected 4 events, got %+v", loaded.Events)
+	}
+	ghost := loaded.History("ghost")
+	if len(ghost) != 2 || ghost[0].Kind != EventInstalled || ghost[1].Error != "failed to pull images" {
+		t.Fatalf("unexpected ghost history: %+v", ghost)
+	}
+	if ghost[0].Time.IsZero() || ghost[0].By != "alice@laptop" {
+		t.Fatalf("expected a timestamp and author, got %+v", ghost[0])
+	}
+	if len(loaded.History("gitea")) != 0 {
+		t.Fatal("expected no history for an app that was never installed")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -138,7 +139,20 @@ func Connect(ctx context.Context, exec executor.Executor) (string, error) {
 // Serve exposes a local port over HTTPS on the tailnet.
 // If Serve is not enabled on the tailnet, it prints the enable URL and waits.
 func Serve(ctx context.Context, exec executor.Executor, port int) error {
-	cmd := fmt.Sprintf("tailscale serve --bg --https=443 http://localhost:%d 2>&1", port)
+	return runServe(ctx, exec, fmt.Sprintf("tailscale serve --bg --https=443 http://localhost:%d 2>&1", port))
+}
+
+// ServePath exposes a local port over HTTPS under a path prefix.
+func ServePath(ctx context.Context, exec executor.Executor, path string, port int) error {
+	return runServe(ctx, exec, fmt.Sprintf("tailscale serve --bg --https=443 --set-path %s http://localhost:%d 2>&1", path, port))
+}
+
+// ServeTCP forwards a raw TCP port on the tailnet to the same local port.
+func ServeTCP(ctx context.Context, exec executor.Executor, port int) error {
+	return runServe(ctx, exec, fmt.Sprintf("tailscale serve --bg --tcp=%d tcp://localhost:%d 2>&1", port, port))
+}
+
+func runServe(ctx context.Context, exec executor.Executor, cmd string) error {
 	out, err := exec.Run(ctx, cmd)
 
 	// If serve is not enabled, show the enable URL and poll until it is
@@ -176,6 +190,64 @@ func Serve(ctx context.Context, exec executor.Executor, port int) error {
 	return nil
 }
 
+// ServeConfig is what Tailscale Serve currently forwards: local HTTP ports
+// with the path they are served under, and forwarded TCP ports.
+type ServeConfig struct {
+	HTTP map[int]string
+	TCP  map[int]bool
+}
+
+// Served reads the Serve configuration from tailscale serve status. A node
+// without tailscale or without Serve set up serves nothing.
+func Served(ctx context.Context, exec executor.Executor) (*ServeConfig, error) {
+	cfg := &ServeConfig{HTTP: make(map[int]string), TCP: make(map[int]bool)}
+	out, _ := exec.Run(ctx, "tailscale serve status --json 2>/dev/null || true")
+	out = strings.TrimSpace(out)
+	if out == "" || out == "{}" {
+		return cfg, nil
+	}
+
+	var status struct {
+		TCP map[string]struct {
+			TCPForward string `json:"TCPForward"`
+		} `json:"TCP"`
+		Web map[string]struct {
+			Handlers map[string]struct {
+				Proxy string `json:"Proxy"`
+			} `json:"Handlers"`
+		} `json:"Web"`
+	}
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		return nil, fmt.Errorf("failed to parse tailscale serve status: %w", err)
+	}
+	for _, t := range status.TCP {
+		if port := localPort(t.TCPForward); port != 0 {
+			cfg.TCP[port] = true
+		}
+	}
+	for _, web := range status.Web {
+		for path, h := range web.Handlers {
+			if port := localPort(h.Proxy); port != 0 {
+				if path == "/" {
+					path = ""
+				}
+				cfg.HTTP[port] = path
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// localPort returns the port of a "localhost:N" or "http://localhost:N" target.
+func localPort(target string) int {
+	i := strings.LastIndex(target, ":")
+	if i < 0 {
+		return 0
+	}
+	port, _ := strconv.Atoi(strings.TrimSuffix(target[i+1:], "/"))
+	return port
+}
+
 // RemoveServe stops serving a local port on the tailnet.
 func RemoveServe(ctx context.Context, exec executor.Executor, port int) error {
 	if _, err := exec.Run(ctx, "tailscale serve --https=443 off"); err != nil {
@@ -183,3 +255,19 @@ func RemoveServe(ctx context.Context, exec executor.Executor, port int) error {
 	}
 	return nil
 }
+
+// RemoveServePath stops serving a path prefix on the tailnet.
+func RemoveServePath(ctx context.Context, exec executor.Executor, path string) error {
+	if _, err := exec.Run(ctx, fmt.Sprintf("tailscale serve --https=443 --set-path %s off", path)); err != nil {
+		return fmt.Errorf("failed to remove tailscale serve: %w", err)
+	}
+	return nil
+}
+
+// RemoveServeTCP stops forwarding a TCP port on the tailnet.
+func RemoveServeTCP(ctx context.Context, exec executor.Executor, port int) error {
+	if _, err := exec.Run(ctx, fmt.Sprintf("tailscale serve --tcp=%d off", port)); err != nil {
+		return fmt.Errorf("failed to remove tailscale serve: %w", err)
+	}
+	return nil
+}
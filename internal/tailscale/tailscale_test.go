@@ -223,3 +223,49 @@ func TestRemoveServe_Error(t *testing.T) {
 		t.Fatalf("expected wrapped error, got: %s", err.Error())
 	}
 }
+
+func TestServePathAndTCP(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	ctx := context.Background()
+
+	if err := ServePath(ctx, mock, "/console", 9001); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ServeTCP(ctx, mock, 2223); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cmd := mock.Calls[0].Args[0].(string); cmd != "tailscale serve --bg --https=443 --set-path /console http://localhost:9001 2>&1" {
+		t.Fatalf("unexpected path serve command: %s", cmd)
+	}
+	if cmd := mock.Calls[1].Args[0].(string); cmd != "tailscale serve --bg --tcp=2223 tcp://localhost:2223 2>&1" {
+		t.Fatalf("unexpected tcp serve command: %s", cmd)
+	}
+}
+
+func TestServed(t *testing.T) {
+	mock := executor.NewMockExecutor()
+	mock.RunOutputs["tailscale serve status --json 2>/dev/null || true"] = `{
+  "TCP": {"443": {"HTTPS": true}, "5432": {"TCPForward": "localhost:5432"}},
+  "Web": {"box.tail1234.ts.net:443": {"Handlers": {
+    "/": {"Proxy": "http://localhost:18789"},
+    "/api": {"Proxy": "http://localhost:18790"}
+  }}}
+}`
+
+	cfg, err := Served(context.Background(), mock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.HTTP) != 2 || cfg.HTTP[18789] != "" || cfg.HTTP[18790] != "/api" {
+		t.Fatalf("unexpected http ports: %v", cfg.HTTP)
+	}
+	if len(cfg.TCP) != 1 || !cfg.TCP[5432] {
+		t.Fatalf("unexpected tcp ports: %v", cfg.TCP)
+	}
+
+	empty, err := Served(context.Background(), executor.NewMockExecutor())
+	if err != nil || len(empty.HTTP) != 0 || len(empty.TCP) != 0 {
+		t.Fatalf("expected nothing served, got %+v, %v", empty, err)
+	}
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/fatih/color"
 )
@@ -69,3 +70,31 @@ func HardeningSummary(host string, sshPort int) {
 	fmt.Printf("    %s\n", green(fmt.Sprintf("bunkr <command> --on bunkr@%s:%d", host, sshPort)))
 	fmt.Println()
 }
+
+// Diff prints a unified diff, coloring added and removed lines.
+func Diff(diff string) {
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			fmt.Printf("    %s\n", green(line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Printf("    %s\n", red(line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Printf("    %s\n", cyan(line))
+		default:
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
+
+// CheckRow prints one row of a pass/warn/fail report.
+func CheckRow(status, subject, check, detail string) {
+	mark := green("✓ pass")
+	switch status {
+	case "warn":
+		mark = yellow("⚠ warn")
+	case "fail":
+		mark = red("✗ fail")
+	}
+	fmt.Printf("  %s  %-16s %-28s %s\n", mark, subject, check, detail)
+}